@@ -0,0 +1,325 @@
+// Package datasource decouples AuthMiddleware from the on-disk
+// test_data_dir layout, so the server can be pointed at a live Fi backend
+// (or anything else) without editing the middleware. It defines the
+// DataSource interface every backend implements, a context carrier for the
+// bearer token AuthMiddleware authenticates with, and a handful of
+// composable implementations (embedded fixtures, a generic REST backend, a
+// TTL cache and a fallback wrapper).
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+// Tool name constants, shared between the DataSource methods below and
+// ToolList so both sides stay in sync.
+const (
+	ToolFetchNetWorth          = "fetch_net_worth"
+	ToolFetchCreditReport      = "fetch_credit_report"
+	ToolFetchEPFDetails        = "fetch_epf_details"
+	ToolFetchMFTransactions    = "fetch_mf_transactions"
+	ToolFetchBankTransactions  = "fetch_bank_transactions"
+	ToolFetchStockTransactions = "fetch_stock_transactions"
+)
+
+// DataSource is implemented by every backend capable of serving a user's Fi
+// Money data for one of the tools in ToolList. Implementations return the
+// raw JSON payload a tool handler sends back to the MCP client.
+type DataSource interface {
+	FetchNetWorth(ctx context.Context, userID string) ([]byte, error)
+	FetchCreditReport(ctx context.Context, userID string) ([]byte, error)
+	FetchEPFDetails(ctx context.Context, userID string) ([]byte, error)
+	FetchMFTransactions(ctx context.Context, userID string) ([]byte, error)
+	FetchBankTransactions(ctx context.Context, userID string) ([]byte, error)
+	FetchStockTransactions(ctx context.Context, userID string) ([]byte, error)
+}
+
+type contextKey string
+
+const accessTokenContextKey contextKey = "datasource_access_token"
+
+// WithAccessToken returns a copy of ctx carrying the bearer token
+// AuthMiddleware authenticated the current session with. HTTPSource reads
+// it back via AccessTokenFromContext.
+func WithAccessToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, accessTokenContextKey, token)
+}
+
+// AccessTokenFromContext returns the bearer token stored by
+// WithAccessToken, or "" if none was set.
+func AccessTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(accessTokenContextKey).(string)
+	return token
+}
+
+// EmbeddedFSSource serves fixtures bundled into the binary via go:embed,
+// replacing the old os.ReadFile("test_data_dir/...") lookup so the data is
+// still reachable on deployments, such as Cloud Run, that don't ship the
+// source tree alongside the binary.
+type EmbeddedFSSource struct {
+	fs   fs.FS
+	root string
+}
+
+// NewEmbeddedFSSource returns a DataSource reading "<root>/<userID>/<tool>.json"
+// out of fsys.
+func NewEmbeddedFSSource(fsys fs.FS, root string) *EmbeddedFSSource {
+	return &EmbeddedFSSource{fs: fsys, root: root}
+}
+
+func (s *EmbeddedFSSource) read(_ context.Context, userID, tool string) ([]byte, error) {
+	data, err := fs.ReadFile(s.fs, path.Join(s.root, userID, tool+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("datasource: reading %s for %s: %w", tool, userID, err)
+	}
+	return data, nil
+}
+
+func (s *EmbeddedFSSource) FetchNetWorth(ctx context.Context, userID string) ([]byte, error) {
+	return s.read(ctx, userID, ToolFetchNetWorth)
+}
+
+func (s *EmbeddedFSSource) FetchCreditReport(ctx context.Context, userID string) ([]byte, error) {
+	return s.read(ctx, userID, ToolFetchCreditReport)
+}
+
+func (s *EmbeddedFSSource) FetchEPFDetails(ctx context.Context, userID string) ([]byte, error) {
+	return s.read(ctx, userID, ToolFetchEPFDetails)
+}
+
+func (s *EmbeddedFSSource) FetchMFTransactions(ctx context.Context, userID string) ([]byte, error) {
+	return s.read(ctx, userID, ToolFetchMFTransactions)
+}
+
+func (s *EmbeddedFSSource) FetchBankTransactions(ctx context.Context, userID string) ([]byte, error) {
+	return s.read(ctx, userID, ToolFetchBankTransactions)
+}
+
+func (s *EmbeddedFSSource) FetchStockTransactions(ctx context.Context, userID string) ([]byte, error) {
+	return s.read(ctx, userID, ToolFetchStockTransactions)
+}
+
+// HTTPSource calls a configurable REST endpoint per tool, authenticating
+// with the bearer token AuthMiddleware placed on ctx. Requests are made to
+// "<BaseURL>/<tool>?userID=<userID>".
+type HTTPSource struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewHTTPSource returns an HTTPSource calling baseURL with a 10s timeout.
+func NewHTTPSource(baseURL string) *HTTPSource {
+	return &HTTPSource{BaseURL: baseURL, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *HTTPSource) fetch(ctx context.Context, tool, userID string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+"/"+tool, nil)
+	if err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	q.Set("userID", userID)
+	req.URL.RawQuery = q.Encode()
+	if token := AccessTokenFromContext(ctx); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("datasource: calling %s: %w", tool, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("datasource: %s returned %d", tool, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("datasource: reading %s response: %w", tool, err)
+	}
+	return data, nil
+}
+
+func (s *HTTPSource) FetchNetWorth(ctx context.Context, userID string) ([]byte, error) {
+	return s.fetch(ctx, ToolFetchNetWorth, userID)
+}
+
+func (s *HTTPSource) FetchCreditReport(ctx context.Context, userID string) ([]byte, error) {
+	return s.fetch(ctx, ToolFetchCreditReport, userID)
+}
+
+func (s *HTTPSource) FetchEPFDetails(ctx context.Context, userID string) ([]byte, error) {
+	return s.fetch(ctx, ToolFetchEPFDetails, userID)
+}
+
+func (s *HTTPSource) FetchMFTransactions(ctx context.Context, userID string) ([]byte, error) {
+	return s.fetch(ctx, ToolFetchMFTransactions, userID)
+}
+
+func (s *HTTPSource) FetchBankTransactions(ctx context.Context, userID string) ([]byte, error) {
+	return s.fetch(ctx, ToolFetchBankTransactions, userID)
+}
+
+func (s *HTTPSource) FetchStockTransactions(ctx context.Context, userID string) ([]byte, error) {
+	return s.fetch(ctx, ToolFetchStockTransactions, userID)
+}
+
+// CachingSource wraps another DataSource with a TTL-based in-memory cache
+// keyed by (userID, tool), so repeated tool calls within the same window
+// don't all round-trip to the underlying backend.
+type CachingSource struct {
+	inner DataSource
+	ttl   time.Duration
+
+	mu    sync.Mutex
+	items map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	data []byte
+	at   time.Time
+}
+
+// NewCachingSource returns a CachingSource caching inner's responses for ttl.
+func NewCachingSource(inner DataSource, ttl time.Duration) *CachingSource {
+	return &CachingSource{inner: inner, ttl: ttl, items: make(map[string]cacheEntry)}
+}
+
+func (c *CachingSource) get(ctx context.Context, tool, userID string, fetch func(context.Context, string) ([]byte, error)) ([]byte, error) {
+	key := userID + "\x00" + tool
+
+	c.mu.Lock()
+	entry, ok := c.items[key]
+	c.mu.Unlock()
+	if ok && time.Since(entry.at) < c.ttl {
+		return entry.data, nil
+	}
+
+	data, err := fetch(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.items[key] = cacheEntry{data: data, at: time.Now()}
+	c.mu.Unlock()
+	return data, nil
+}
+
+func (c *CachingSource) FetchNetWorth(ctx context.Context, userID string) ([]byte, error) {
+	return c.get(ctx, ToolFetchNetWorth, userID, c.inner.FetchNetWorth)
+}
+
+func (c *CachingSource) FetchCreditReport(ctx context.Context, userID string) ([]byte, error) {
+	return c.get(ctx, ToolFetchCreditReport, userID, c.inner.FetchCreditReport)
+}
+
+func (c *CachingSource) FetchEPFDetails(ctx context.Context, userID string) ([]byte, error) {
+	return c.get(ctx, ToolFetchEPFDetails, userID, c.inner.FetchEPFDetails)
+}
+
+func (c *CachingSource) FetchMFTransactions(ctx context.Context, userID string) ([]byte, error) {
+	return c.get(ctx, ToolFetchMFTransactions, userID, c.inner.FetchMFTransactions)
+}
+
+func (c *CachingSource) FetchBankTransactions(ctx context.Context, userID string) ([]byte, error) {
+	return c.get(ctx, ToolFetchBankTransactions, userID, c.inner.FetchBankTransactions)
+}
+
+func (c *CachingSource) FetchStockTransactions(ctx context.Context, userID string) ([]byte, error) {
+	return c.get(ctx, ToolFetchStockTransactions, userID, c.inner.FetchStockTransactions)
+}
+
+// FallbackSource tries primary first and, if it returns an error, falls
+// back to secondary. This lets a live backend be wired up without an outage
+// there taking every tool down with it - callers typically pass an
+// EmbeddedFSSource as secondary.
+type FallbackSource struct {
+	primary   DataSource
+	secondary DataSource
+}
+
+// NewFallbackSource returns a FallbackSource preferring primary over secondary.
+func NewFallbackSource(primary, secondary DataSource) *FallbackSource {
+	return &FallbackSource{primary: primary, secondary: secondary}
+}
+
+func (s *FallbackSource) call(primary, secondary func(context.Context, string) ([]byte, error), ctx context.Context, userID string) ([]byte, error) {
+	data, err := primary(ctx, userID)
+	if err == nil {
+		return data, nil
+	}
+	return secondary(ctx, userID)
+}
+
+func (s *FallbackSource) FetchNetWorth(ctx context.Context, userID string) ([]byte, error) {
+	return s.call(s.primary.FetchNetWorth, s.secondary.FetchNetWorth, ctx, userID)
+}
+
+func (s *FallbackSource) FetchCreditReport(ctx context.Context, userID string) ([]byte, error) {
+	return s.call(s.primary.FetchCreditReport, s.secondary.FetchCreditReport, ctx, userID)
+}
+
+func (s *FallbackSource) FetchEPFDetails(ctx context.Context, userID string) ([]byte, error) {
+	return s.call(s.primary.FetchEPFDetails, s.secondary.FetchEPFDetails, ctx, userID)
+}
+
+func (s *FallbackSource) FetchMFTransactions(ctx context.Context, userID string) ([]byte, error) {
+	return s.call(s.primary.FetchMFTransactions, s.secondary.FetchMFTransactions, ctx, userID)
+}
+
+func (s *FallbackSource) FetchBankTransactions(ctx context.Context, userID string) ([]byte, error) {
+	return s.call(s.primary.FetchBankTransactions, s.secondary.FetchBankTransactions, ctx, userID)
+}
+
+func (s *FallbackSource) FetchStockTransactions(ctx context.Context, userID string) ([]byte, error) {
+	return s.call(s.primary.FetchStockTransactions, s.secondary.FetchStockTransactions, ctx, userID)
+}
+
+// FakeSource is a DataSource for tests: Responses is keyed by tool name and
+// returned verbatim; Errors, also keyed by tool name, is returned instead
+// when set. Calls is appended to on every fetch so tests can assert on call
+// counts, e.g. for CachingSource.
+type FakeSource struct {
+	Responses map[string][]byte
+	Errors    map[string]error
+	Calls     []string
+}
+
+func (f *FakeSource) fetch(tool string) ([]byte, error) {
+	f.Calls = append(f.Calls, tool)
+	if err, ok := f.Errors[tool]; ok {
+		return nil, err
+	}
+	return f.Responses[tool], nil
+}
+
+func (f *FakeSource) FetchNetWorth(context.Context, string) ([]byte, error) {
+	return f.fetch(ToolFetchNetWorth)
+}
+
+func (f *FakeSource) FetchCreditReport(context.Context, string) ([]byte, error) {
+	return f.fetch(ToolFetchCreditReport)
+}
+
+func (f *FakeSource) FetchEPFDetails(context.Context, string) ([]byte, error) {
+	return f.fetch(ToolFetchEPFDetails)
+}
+
+func (f *FakeSource) FetchMFTransactions(context.Context, string) ([]byte, error) {
+	return f.fetch(ToolFetchMFTransactions)
+}
+
+func (f *FakeSource) FetchBankTransactions(context.Context, string) ([]byte, error) {
+	return f.fetch(ToolFetchBankTransactions)
+}
+
+func (f *FakeSource) FetchStockTransactions(context.Context, string) ([]byte, error) {
+	return f.fetch(ToolFetchStockTransactions)
+}
@@ -0,0 +1,97 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestEmbeddedFSSource(t *testing.T) {
+	fsys := fstest.MapFS{
+		"test_data_dir/1111111111/fetch_net_worth.json": &fstest.MapFile{Data: []byte(`{"net_worth":1}`)},
+	}
+	s := NewEmbeddedFSSource(fsys, "test_data_dir")
+
+	data, err := s.FetchNetWorth(context.Background(), "1111111111")
+	if err != nil {
+		t.Fatalf("FetchNetWorth returned error: %v", err)
+	}
+	if string(data) != `{"net_worth":1}` {
+		t.Errorf("data = %s, want net worth fixture", data)
+	}
+
+	if _, err := s.FetchCreditReport(context.Background(), "1111111111"); err == nil {
+		t.Error("expected error for missing fixture, got nil")
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	var gotAuth, gotTool string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotTool = r.URL.Path
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	s := NewHTTPSource(srv.URL)
+	ctx := WithAccessToken(context.Background(), "at-123")
+	data, err := s.FetchNetWorth(ctx, "1111111111")
+	if err != nil {
+		t.Fatalf("FetchNetWorth returned error: %v", err)
+	}
+	if string(data) != `{"ok":true}` {
+		t.Errorf("data = %s, want {\"ok\":true}", data)
+	}
+	if gotAuth != "Bearer at-123" {
+		t.Errorf("Authorization header = %q, want Bearer at-123", gotAuth)
+	}
+	if gotTool != "/"+ToolFetchNetWorth {
+		t.Errorf("path = %q, want /%s", gotTool, ToolFetchNetWorth)
+	}
+}
+
+func TestCachingSourceCachesWithinTTL(t *testing.T) {
+	fake := &FakeSource{Responses: map[string][]byte{ToolFetchNetWorth: []byte("v1")}}
+	c := NewCachingSource(fake, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.FetchNetWorth(context.Background(), "u1"); err != nil {
+			t.Fatalf("FetchNetWorth returned error: %v", err)
+		}
+	}
+	if len(fake.Calls) != 1 {
+		t.Errorf("inner source called %d times, want 1", len(fake.Calls))
+	}
+}
+
+func TestCachingSourceRefetchesAfterTTL(t *testing.T) {
+	fake := &FakeSource{Responses: map[string][]byte{ToolFetchNetWorth: []byte("v1")}}
+	c := NewCachingSource(fake, time.Nanosecond)
+
+	c.FetchNetWorth(context.Background(), "u1")
+	time.Sleep(time.Millisecond)
+	c.FetchNetWorth(context.Background(), "u1")
+
+	if len(fake.Calls) != 2 {
+		t.Errorf("inner source called %d times, want 2", len(fake.Calls))
+	}
+}
+
+func TestFallbackSourceUsesSecondaryOnError(t *testing.T) {
+	primary := &FakeSource{Errors: map[string]error{ToolFetchNetWorth: errors.New("down")}}
+	secondary := &FakeSource{Responses: map[string][]byte{ToolFetchNetWorth: []byte("fixture")}}
+	s := NewFallbackSource(primary, secondary)
+
+	data, err := s.FetchNetWorth(context.Background(), "u1")
+	if err != nil {
+		t.Fatalf("FetchNetWorth returned error: %v", err)
+	}
+	if string(data) != "fixture" {
+		t.Errorf("data = %s, want fixture", data)
+	}
+}
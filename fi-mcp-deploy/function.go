@@ -3,16 +3,25 @@ package main
 import (
 	"context"
 	"embed"
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
 	"strings"
-
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"example.com/fi-mcp-server/datasource"
+	"example.com/fi-mcp-server/notifiers"
+	"example.com/fi-mcp-server/oidc"
+	"example.com/fi-mcp-server/session"
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/redis/go-redis/v9"
 	"github.com/samber/lo"
 )
 
@@ -22,48 +31,143 @@ var staticFiles embed.FS
 //go:embed test_data_dir/*
 var testDataFiles embed.FS
 
-// ToolInfo holds the name and description of a tool
+// ToolInfo holds the name, description and handler binding of a tool.
+// Most tools read data: Fetch dispatches to the method of source that
+// serves it. A few tools (notification subscriptions) have side effects
+// that don't fit that shape, so they set Handler directly instead; Options
+// carries any extra mcp.ToolOption the tool's parameters need.
 type ToolInfo struct {
 	Name        string
 	Description string
+	Fetch       func(source datasource.DataSource, ctx context.Context, userID string) ([]byte, error)
+	Handler     server.ToolHandlerFunc
+	Options     []mcp.ToolOption
 }
 
 // ToolList is the list of all tools and their descriptions
 var ToolList = []ToolInfo{
 	{
-		Name:        "fetch_net_worth",
+		Name:        datasource.ToolFetchNetWorth,
 		Description: "Calculate comprehensive net worth using ONLY actual data from accounts users connected on Fi Money including: Bank account balances, Mutual fund investment holdings, Indian Stocks investment holdings, Total US Stocks investment (If investing through Fi Money app), EPF account balances, Credit card debt and loan balances (if credit report connected), Any other assets/liabilities linked to Fi Money platform.",
+		Fetch:       datasource.DataSource.FetchNetWorth,
 	},
 	{
-		Name:        "fetch_credit_report",
+		Name:        datasource.ToolFetchCreditReport,
 		Description: "Retrieve comprehensive credit report including scores, active loans, credit card utilization, payment history, date of birth and recent inquiries from connected credit bureaus.",
+		Fetch:       datasource.DataSource.FetchCreditReport,
 	},
 	{
-		Name:        "fetch_epf_details",
+		Name:        datasource.ToolFetchEPFDetails,
 		Description: "Retrieve detailed EPF (Employee Provident Fund) account information including: Account balance and contributions, Employer and employee contribution history, Interest earned and credited amounts.",
+		Fetch:       datasource.DataSource.FetchEPFDetails,
 	},
 	{
-		Name:        "fetch_mf_transactions",
+		Name:        datasource.ToolFetchMFTransactions,
 		Description: "Retrieve detailed transaction history from accounts connected to Fi Money platform including: Mutual fund transactions.",
+		Fetch:       datasource.DataSource.FetchMFTransactions,
 	},
 	{
-		Name:        "fetch_bank_transactions",
+		Name:        datasource.ToolFetchBankTransactions,
 		Description: "Retrieve detailed bank transactions for each bank account connected to Fi money platform.",
+		Fetch:       datasource.DataSource.FetchBankTransactions,
 	},
 	{
-		Name:        "fetch_stock_transactions",
+		Name:        datasource.ToolFetchStockTransactions,
 		Description: "Retrieve detailed indian stock transactions for all connected indian stock accounts to Fi money platform.",
+		Fetch:       datasource.DataSource.FetchStockTransactions,
+	},
+	{
+		Name:        "create_notification_subscription",
+		Description: "Subscribe to alerts about material changes in the user's financial data - a net worth drop, a new credit inquiry, a large mutual fund transaction or an EPF credit - instead of only pulling it on demand.",
+		Options: []mcp.ToolOption{
+			mcp.WithString("topic", mcp.Required(), mcp.Description("One of: net_worth_dropped_more_than_pct, new_credit_inquiry, mf_transaction_above_amount, epf_credit_received.")),
+			mcp.WithString("channel", mcp.Required(), mcp.Description("Delivery channel: smtp, webhook or mcp_resource.")),
+			mcp.WithString("target", mcp.Description("Recipient email for smtp, URL for webhook. Unused for mcp_resource.")),
+		},
+		Handler: createSubscriptionHandler,
+	},
+	{
+		Name:        "list_notification_subscriptions",
+		Description: "List the calling user's active notification subscriptions.",
+		Handler:     listSubscriptionsHandler,
 	},
+	{
+		Name:        "delete_notification_subscription",
+		Description: "Cancel a previously created notification subscription.",
+		Options: []mcp.ToolOption{
+			mcp.WithString("subscription_id", mcp.Required(), mcp.Description("The ID returned by create_notification_subscription.")),
+		},
+		Handler: deleteSubscriptionHandler,
+	},
+}
+
+type contextKey string
+
+const identityContextKey contextKey = "phone_number"
+
+// pendingAuthorization tracks one in-flight /authorize -> /callback round
+// trip, keyed by the OAuth "state" parameter.
+type pendingAuthorization struct {
+	SessionID    string
+	IssuerName   string
+	Nonce        string
+	CodeVerifier string
+	CreatedAt    time.Time
 }
 
+// pendingAuthTTL bounds how long a user has to complete the login redirect
+// before the state/nonce/verifier are discarded.
+const pendingAuthTTL = 10 * time.Minute
+
+// pendingJanitorInterval is how often AuthMiddleware sweeps m.pending for
+// entries past pendingAuthTTL. /authorize accepts an unauthenticated,
+// attacker-chosen sessionId, and completeAuthorization is the only other
+// thing that removes a pending entry, so without this an abandoned or
+// flooded flow would accumulate entries in m.pending forever.
+const pendingJanitorInterval = time.Minute
+
+// sessionTTL is how long a logged-in session.Store entry lives without a
+// refresh. It is kept well above any single access token's lifetime so a
+// session only disappears from the store once refreshSession itself gives
+// up (unknown issuer, revoked refresh token, and so on).
+const sessionTTL = 30 * 24 * time.Hour
+
 // AuthMiddleware handles authentication for the MCP server
 type AuthMiddleware struct {
-	sessionStore map[string]string
+	oidcManager   *oidc.Manager
+	defaultIssuer string
+	sessions      session.Store
+
+	mu      sync.Mutex
+	pending map[string]*pendingAuthorization
+}
+
+func NewAuthMiddleware(mgr *oidc.Manager, defaultIssuer string, sessions session.Store) *AuthMiddleware {
+	m := &AuthMiddleware{
+		oidcManager:   mgr,
+		defaultIssuer: defaultIssuer,
+		sessions:      sessions,
+		pending:       make(map[string]*pendingAuthorization),
+	}
+	go m.pendingJanitor()
+	return m
 }
 
-func NewAuthMiddleware() *AuthMiddleware {
-	return &AuthMiddleware{
-		sessionStore: make(map[string]string),
+// pendingJanitor evicts m.pending entries older than pendingAuthTTL on a
+// fixed interval, for flows that are abandoned rather than completed or
+// rejected outright.
+func (m *AuthMiddleware) pendingJanitor() {
+	ticker := time.NewTicker(pendingJanitorInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		now := time.Now()
+		m.mu.Lock()
+		for state, p := range m.pending {
+			if now.Sub(p.CreatedAt) > pendingAuthTTL {
+				delete(m.pending, state)
+			}
+		}
+		m.mu.Unlock()
 	}
 }
 
@@ -72,41 +176,225 @@ var loginRequiredJson = `{"status": "login_required","login_url": "%s","message"
 func (m *AuthMiddleware) AuthMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
 	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		sessionId := server.ClientSessionFromContext(ctx).SessionID()
-		phoneNumber, ok := m.sessionStore[sessionId]
-		if !ok {
+
+		sess := m.getSession(ctx, sessionId)
+		if sess == nil {
 			loginUrl := m.getLoginUrl(sessionId)
 			return mcp.NewToolResultText(fmt.Sprintf(loginRequiredJson, loginUrl)), nil
 		}
-		if !lo.Contains(GetAllowedMobileNumbers(), phoneNumber) {
-			return mcp.NewToolResultError("phone number is not allowed"), nil
+
+		if time.Now().After(sess.ExpiresAt) {
+			refreshed, err := m.refreshSession(ctx, sessionId, sess)
+			if err != nil {
+				log.Println("error refreshing session", err)
+				m.deleteSession(ctx, sessionId, sess.Identity)
+				loginUrl := m.getLoginUrl(sessionId)
+				return mcp.NewToolResultText(fmt.Sprintf(loginRequiredJson, loginUrl)), nil
+			}
+			sess = refreshed
 		}
-		ctx = context.WithValue(ctx, "phone_number", phoneNumber)
-		toolName := req.Params.Name
-		data, readErr := os.ReadFile("test_data_dir/" + phoneNumber + "/" + toolName + ".json")
-		if readErr != nil {
-			log.Println("error reading test data file", readErr)
-			return mcp.NewToolResultError("error reading test data file"), nil
+
+		// GetAllowedMobileNumbers only lists the test_data_dir fixture
+		// directories, so it can only ever gate phone-number identities;
+		// a generic OIDC issuer that identifies users by "sub" has no
+		// fixture directory to match against and is allowed through here
+		// unchecked, same as any other DataSource-backed deployment.
+		if sess.IdentityClaim == "phone_number" && !lo.Contains(GetAllowedMobileNumbers(), sess.Identity) {
+			return mcp.NewToolResultError("phone number is not allowed"), nil
 		}
-		return mcp.NewToolResultText(string(data)), nil
+
+		ctx = context.WithValue(ctx, identityContextKey, sess.Identity)
+		ctx = datasource.WithAccessToken(ctx, sess.AccessToken)
+
+		return next(ctx, req)
 	}
 }
 
+func (m *AuthMiddleware) getSession(ctx context.Context, sessionId string) *session.Session {
+	sess, ok, err := m.sessions.Get(ctx, sessionId)
+	if err != nil {
+		log.Println("error reading session", err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return sess
+}
+
+func (m *AuthMiddleware) deleteSession(ctx context.Context, sessionId, identity string) {
+	if err := m.sessions.Delete(ctx, sessionId); err != nil {
+		log.Println("error deleting session", err)
+	}
+	if watcher != nil {
+		watcher.Stop(sessionId)
+	}
+	unregisterUserResources(identity)
+}
+
+// onSessionUnregistered is wired to mcp-go's OnUnregisterSession hook, which
+// fires when a streamable-HTTP client disconnects. There's no /logout
+// endpoint, so this is the only reliable signal that a session is gone for
+// good; without it, a client that simply closes its connection (the common
+// case - a new MCP session per conversation) would leave its watcher
+// goroutine polling and its resources registered for the life of the
+// process. It doesn't delete the session.Store entry itself - that still
+// expires on sessionTTL the normal way - only the in-process state keyed by
+// sessionId.
+func onSessionUnregistered(ctx context.Context, sessionId string) {
+	sess := authMiddleware.getSession(ctx, sessionId)
+	if watcher != nil {
+		watcher.Stop(sessionId)
+	}
+	if sess != nil {
+		unregisterUserResources(sess.Identity)
+	}
+}
+
+func (m *AuthMiddleware) refreshSession(ctx context.Context, sessionId string, sess *session.Session) (*session.Session, error) {
+	iss, ok := m.oidcManager.Issuer(sess.IssuerName)
+	if !ok {
+		return nil, fmt.Errorf("unknown issuer %q", sess.IssuerName)
+	}
+	tok, err := m.oidcManager.RefreshToken(ctx, iss, sess.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	updated := &session.Session{
+		UserID:        sess.UserID,
+		Identity:      sess.Identity,
+		IdentityClaim: sess.IdentityClaim,
+		IssuerName:    sess.IssuerName,
+		IssuedAt:      time.Now(),
+		ExpiresAt:     time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second),
+		Scopes:        sess.Scopes,
+		TokenBundle: session.TokenBundle{
+			AccessToken:  tok.AccessToken,
+			RefreshToken: sess.RefreshToken,
+		},
+	}
+	if tok.RefreshToken != "" {
+		updated.RefreshToken = tok.RefreshToken
+	}
+
+	if err := m.sessions.Refresh(ctx, sessionId, updated, sessionTTL); err != nil {
+		return nil, fmt.Errorf("persisting refreshed session: %w", err)
+	}
+	return updated, nil
+}
+
 func (m *AuthMiddleware) getLoginUrl(sessionId string) string {
-	// For Cloud Functions, use the function URL if available
-	if functionURL := os.Getenv("FUNCTION_URL"); functionURL != "" {
-		return fmt.Sprintf("%s/mockWebPage?sessionId=%s", functionURL, sessionId)
+	functionURL := os.Getenv("FUNCTION_URL")
+	if functionURL == "" {
+		functionURL = "https://YOUR-FUNCTION-URL"
 	}
-	// Fallback to generic URL that the user can update
-	return fmt.Sprintf("https://YOUR-FUNCTION-URL/mockWebPage?sessionId=%s", sessionId)
+	return fmt.Sprintf("%s/authorize?sessionId=%s", functionURL, sessionId)
 }
 
-func (m *AuthMiddleware) AddSession(sessionId, phoneNumber string) {
-	m.sessionStore[sessionId] = phoneNumber
+// beginAuthorization starts an authorization-code + PKCE flow for
+// sessionId against issuerName (or the configured default issuer), and
+// returns the URL to redirect the user's browser to.
+func (m *AuthMiddleware) beginAuthorization(ctx context.Context, sessionId, issuerName string) (string, error) {
+	if issuerName == "" {
+		issuerName = m.defaultIssuer
+	}
+	iss, ok := m.oidcManager.Issuer(issuerName)
+	if !ok {
+		return "", fmt.Errorf("unknown issuer %q", issuerName)
+	}
+
+	state, err := oidc.GenerateState()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.GenerateState()
+	if err != nil {
+		return "", err
+	}
+	verifier, challenge, err := oidc.NewPKCEVerifier()
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	m.pending[state] = &pendingAuthorization{
+		SessionID:    sessionId,
+		IssuerName:   issuerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		CreatedAt:    time.Now(),
+	}
+	m.mu.Unlock()
+
+	return m.oidcManager.AuthCodeURL(ctx, iss, state, nonce, challenge)
+}
+
+// completeAuthorization exchanges the code received at /callback for
+// tokens, verifies the ID token and stores the resulting session.Session.
+func (m *AuthMiddleware) completeAuthorization(ctx context.Context, state, code string) (*session.Session, error) {
+	m.mu.Lock()
+	p, ok := m.pending[state]
+	if ok {
+		delete(m.pending, state)
+	}
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown or expired state")
+	}
+	if time.Since(p.CreatedAt) > pendingAuthTTL {
+		return nil, fmt.Errorf("authorization attempt expired, please try again")
+	}
+
+	iss, ok := m.oidcManager.Issuer(p.IssuerName)
+	if !ok {
+		return nil, fmt.Errorf("unknown issuer %q", p.IssuerName)
+	}
+
+	tok, err := m.oidcManager.ExchangeCode(ctx, iss, code, p.CodeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging code: %w", err)
+	}
+	claims, err := m.oidcManager.VerifyIDToken(ctx, iss, tok.IDToken, p.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("verifying id token: %w", err)
+	}
+
+	identity := claims.PhoneNumber
+	identityClaim := "phone_number"
+	if identity == "" {
+		identity = claims.Subject
+		identityClaim = "sub"
+	}
+
+	sess := &session.Session{
+		UserID:        identity,
+		Identity:      identity,
+		IdentityClaim: identityClaim,
+		IssuerName:    p.IssuerName,
+		IssuedAt:      time.Now(),
+		ExpiresAt:     time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second),
+		Scopes:        strings.Fields(tok.Scope),
+		TokenBundle: session.TokenBundle{
+			AccessToken:  tok.AccessToken,
+			RefreshToken: tok.RefreshToken,
+		},
+	}
+
+	if err := m.sessions.Put(ctx, p.SessionID, sess, sessionTTL); err != nil {
+		return nil, fmt.Errorf("storing session: %w", err)
+	}
+
+	if watcher != nil && notifierDispatcher != nil && activeDataSource != nil {
+		watcher.Start(p.SessionID, identity, activeDataSource, notifierDispatcher, notifierStore)
+	}
+	registerUserResources(identity)
+
+	return sess, nil
 }
 
 // GetAllowedMobileNumbers returns a slice of directory names in test_data_dir
 func GetAllowedMobileNumbers() []string {
-	dirEntries, err := os.ReadDir("test_data_dir")
+	dirEntries, err := testDataFiles.ReadDir("test_data_dir")
 	if err != nil {
 		return nil
 	}
@@ -120,20 +408,150 @@ func GetAllowedMobileNumbers() []string {
 }
 
 var authMiddleware *AuthMiddleware
-var mcpServer *server.Server
+var mcpServer *server.MCPServer
+
+// activeDataSource is the DataSource setupMCPServer registered tools
+// against; sessionWatcher re-reads it on the same polling cadence so it can
+// diff successive responses for the notifiers subsystem.
+var activeDataSource datasource.DataSource
 
 func init() {
-	authMiddleware = NewAuthMiddleware()
-	mcpServer = setupMCPServer()
+	activeDataSource = buildDataSourceFromEnv()
+	notifierStore = notifiers.NewInMemoryStore()
+	notifierDispatcher = notifiers.NewDispatcher(notifierStore, buildNotifierChannelsFromEnv())
+	watcher = newSessionWatcher()
+
+	mgr, defaultIssuer := loadOIDCManagerFromEnv()
+	authMiddleware = NewAuthMiddleware(mgr, defaultIssuer, buildSessionStoreFromEnv())
+	mcpServer = setupMCPServer(activeDataSource)
 
 	// Register the Cloud Function
 	functions.HTTP("FiMCPFunction", handleRequest)
 }
 
-func setupMCPServer() *server.Server {
+// issuerConfig mirrors the JSON shape accepted by OIDC_ISSUERS.
+type issuerConfig struct {
+	Name         string   `json:"name"`
+	IssuerURL    string   `json:"issuer_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+}
+
+// loadOIDCManagerFromEnv builds an oidc.Manager from either OIDC_ISSUERS (a
+// JSON array, for multiple configured issuers) or a single set of
+// OIDC_ISSUER_* variables, and returns it alongside the name of the issuer
+// /authorize should use when none is specified.
+func loadOIDCManagerFromEnv() (*oidc.Manager, string) {
+	mgr := oidc.NewManager()
+	defaultIssuer := os.Getenv("OIDC_DEFAULT_ISSUER")
+
+	if raw := os.Getenv("OIDC_ISSUERS"); raw != "" {
+		var configs []issuerConfig
+		if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+			log.Fatalf("invalid OIDC_ISSUERS: %v", err)
+		}
+		for _, c := range configs {
+			mgr.RegisterIssuer(&oidc.Issuer{
+				Name:         c.Name,
+				IssuerURL:    c.IssuerURL,
+				ClientID:     c.ClientID,
+				ClientSecret: c.ClientSecret,
+				RedirectURL:  c.RedirectURL,
+				Scopes:       c.Scopes,
+			})
+			if defaultIssuer == "" {
+				defaultIssuer = c.Name
+			}
+		}
+		return mgr, defaultIssuer
+	}
+
+	name := envOrDefault("OIDC_ISSUER_NAME", "default")
+	scopes := strings.Split(envOrDefault("OIDC_SCOPES", "openid,phone"), ",")
+	mgr.RegisterIssuer(&oidc.Issuer{
+		Name:         name,
+		IssuerURL:    os.Getenv("OIDC_ISSUER_URL"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       scopes,
+	})
+	if defaultIssuer == "" {
+		defaultIssuer = name
+	}
+	return mgr, defaultIssuer
+}
+
+func envOrDefault(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// buildDataSourceFromEnv assembles the DataSource setupMCPServer registers
+// tools against. It always has the embedded test_data_dir fixtures as a
+// base; if FI_DATA_SOURCE_URL is set it prefers a live HTTPSource, falling
+// back to the embedded fixtures on error unless
+// FI_DATA_SOURCE_FALLBACK_TO_EMBEDDED=false. FI_DATA_SOURCE_CACHE_TTL wraps
+// the result in a CachingSource.
+func buildDataSourceFromEnv() datasource.DataSource {
+	var source datasource.DataSource = datasource.NewEmbeddedFSSource(testDataFiles, "test_data_dir")
+
+	if baseURL := os.Getenv("FI_DATA_SOURCE_URL"); baseURL != "" {
+		httpSource := datasource.NewHTTPSource(baseURL)
+		if envOrDefault("FI_DATA_SOURCE_FALLBACK_TO_EMBEDDED", "true") == "true" {
+			source = datasource.NewFallbackSource(httpSource, source)
+		} else {
+			source = httpSource
+		}
+	}
+
+	if ttl := os.Getenv("FI_DATA_SOURCE_CACHE_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			log.Printf("invalid FI_DATA_SOURCE_CACHE_TTL %q, ignoring: %v", ttl, err)
+		} else {
+			source = datasource.NewCachingSource(source, d)
+		}
+	}
+
+	return source
+}
+
+// buildSessionStoreFromEnv selects the session.Store this instance keeps
+// logged-in sessions in. SESSION_STORE_BACKEND defaults to "memory", which
+// is fine for a single running instance; "redis" and "firestore" back
+// sessions with a shared store so logins survive a restart or are visible
+// across more than one instance.
+func buildSessionStoreFromEnv() session.Store {
+	switch envOrDefault("SESSION_STORE_BACKEND", "memory") {
+	case "redis":
+		client := redis.NewClient(&redis.Options{Addr: os.Getenv("SESSION_REDIS_ADDR")})
+		return session.NewRedisStore(client, envOrDefault("SESSION_REDIS_PREFIX", "fi-mcp:session:"))
+	case "firestore":
+		client, err := firestore.NewClient(context.Background(), os.Getenv("GOOGLE_CLOUD_PROJECT"))
+		if err != nil {
+			log.Fatalf("creating firestore client: %v", err)
+		}
+		return session.NewFirestoreStore(client, envOrDefault("SESSION_FIRESTORE_COLLECTION", "fi-mcp-sessions"))
+	default:
+		return session.NewInMemoryStore()
+	}
+}
+
+func setupMCPServer(source datasource.DataSource) *server.MCPServer {
+	hooks := &server.Hooks{}
+	hooks.AddOnUnregisterSession(func(ctx context.Context, sess server.ClientSession) {
+		onSessionUnregistered(ctx, sess.SessionID())
+	})
+
 	s := server.NewMCPServer(
 		"Fi MCP Server",
 		"1.0.0",
+		server.WithHooks(hooks),
 		server.WithInstructions("A financial portfolio management MCP server that provides secure access to users' financial data through Fi Money, a financial hub for all things money. This MCP server enables users to:\n- Access comprehensive net worth analysis with asset/liability breakdowns\n- Retrieve detailed transaction histories for mutual funds and Employee Provident Fund accounts\n- View credit reports with scores, loan details, and account histories, this also contains user's date of birth that can be used for calculating their age\n\nIf the person asks, you can tell about Fi Money that it is money management platform that offers below services in partnership with regulated entities:\n\nAVAILABLE SERVICES:\n- Digital savings account with zero Forex cards\n- Invest in Indian Mutual funds, US Stocks (partnership with licensed brokers), Smart and Fixed Deposits.\n- Instant Personal Loans \n- Faster UPI and Bank Transfers payments\n- Credit score monitoring and reports\n\nIMPORTANT LIMITATIONS:\n- This MCP server retrieves only actual user data via Net worth tracker and based on consent provided by the user  and does not generate hypothetical or estimated financial information\n- In this version of the MCP server, user's historical bank transactions, historical stocks transaction data, salary (unless categorically declared) is not present. Don't assume these data points for any kind of analysis.\n\nCRITICAL INSTRUCTIONS FOR FINANCIAL DATA:\n\n1. DATA BOUNDARIES: Only provide information that exists in the user's Fi Money Net worth tracker. Never estimate, extrapolate, or generate hypothetical financial data.\n\n2. SPENDING ANALYSIS: If user asks about spending patterns, categories, or analysis tell the user we currently don't offer that data through the MCP:\n   - For detailed spending insights, direct them to: \"For comprehensive spending analysis and categorization, please use the Fi Money mobile app which provides detailed spending insights and budgeting tools.\"\n\n3. MISSING DATA HANDLING: If requested data is not available:\n   - Clearly state what data is missing\n   - Explain how user can connect additional accounts in Fi Money app\n   - Never fill gaps with estimated or generic information\n"),
 		server.WithToolCapabilities(true),
 		server.WithResourceCapabilities(true, true),
@@ -141,14 +559,35 @@ func setupMCPServer() *server.Server {
 		server.WithToolHandlerMiddleware(authMiddleware.AuthMiddleware),
 	)
 
-	// Register tools from ToolList
+	// Register tools from ToolList: most dispatch to source through their
+	// Fetch binding, the rest bring their own Handler.
 	for _, tool := range ToolList {
-		s.AddTool(mcp.NewTool(tool.Name, mcp.WithDescription(tool.Description)), dummyHandler)
+		opts := append([]mcp.ToolOption{mcp.WithDescription(tool.Description)}, tool.Options...)
+		handler := tool.Handler
+		if handler == nil {
+			handler = toolHandler(source, tool)
+		}
+		s.AddTool(mcp.NewTool(tool.Name, opts...), handler)
 	}
 
 	return s
 }
 
+// toolHandler returns the ToolHandlerFunc for tool, reading the
+// authenticated identity AuthMiddleware placed on ctx and fetching the
+// tool's data from source.
+func toolHandler(source datasource.DataSource, tool ToolInfo) server.ToolHandlerFunc {
+	return func(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		userID, _ := ctx.Value(identityContextKey).(string)
+		data, err := tool.Fetch(source, ctx, userID)
+		if err != nil {
+			log.Printf("error fetching %s for %s: %v", tool.Name, userID, err)
+			return mcp.NewToolResultError("error fetching data"), nil
+		}
+		return mcp.NewToolResultText(string(data)), nil
+	}
+}
+
 func handleRequest(w http.ResponseWriter, r *http.Request) {
 	// Handle static file requests
 	if r.URL.Path == "/static/" || strings.HasPrefix(r.URL.Path, "/static/") {
@@ -161,16 +600,26 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 		streamableServer := server.NewStreamableHTTPServer(mcpServer,
 			server.WithEndpointPath("/stream"),
 		)
+		if r.Method == http.MethodPost && isResourcesListRequest(r) {
+			serveFilteredResourcesList(streamableServer, w, r)
+			return
+		}
 		streamableServer.ServeHTTP(w, r)
 		return
 	}
 
+	// Handle notifiers admin endpoints
+	if strings.HasPrefix(r.URL.Path, "/notifiers/") {
+		notifiersAdminHandler(w, r)
+		return
+	}
+
 	// Handle auth endpoints
 	switch r.URL.Path {
-	case "/mockWebPage":
-		webPageHandler(w, r)
-	case "/login":
-		loginHandler(w, r)
+	case "/authorize":
+		authorizeHandler(w, r)
+	case "/callback":
+		callbackHandler(w, r)
 	default:
 		// Root handler - provide basic info about the MCP server
 		if r.URL.Path == "/" || r.URL.Path == "" {
@@ -183,11 +632,11 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 				"description": "Financial data MCP server for Fi Money platform",
 				"endpoints": {
 					"mcp_stream": "%s/mcp/stream",
-					"login_page": "%s/mockWebPage?sessionId=YOUR_SESSION_ID",
-					"login_endpoint": "%s/login"
+					"login_page": "%s/authorize?sessionId=YOUR_SESSION_ID",
+					"callback": "%s/callback"
 				},
 				"allowed_phone_numbers": %d,
-				"instructions": "Use the MCP stream endpoint to connect via MCP client. Authentication required via login page."
+				"instructions": "Use the MCP stream endpoint to connect via MCP client. Authentication required via the login page."
 			}`, functionURL, functionURL, functionURL, len(GetAllowedMobileNumbers()))
 			fmt.Fprint(w, response)
 		} else {
@@ -221,75 +670,57 @@ func handleStaticFiles(w http.ResponseWriter, r *http.Request) {
 	w.Write(content)
 }
 
-func dummyHandler(_ context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	return mcp.NewToolResultText("dummy handler"), nil
-}
-
-func webPageHandler(w http.ResponseWriter, r *http.Request) {
+// authorizeHandler starts the OAuth 2.0 authorization-code + PKCE flow by
+// redirecting the user to the configured OIDC provider. It replaces the old
+// mock phone-number form.
+func authorizeHandler(w http.ResponseWriter, r *http.Request) {
 	sessionId := r.URL.Query().Get("sessionId")
 	if sessionId == "" {
 		http.Error(w, "sessionId is required", http.StatusBadRequest)
 		return
 	}
+	issuerName := r.URL.Query().Get("issuer")
 
-	// Read template from embedded filesystem
-	templateContent, err := staticFiles.ReadFile("static/login.html")
+	redirectURL, err := authMiddleware.beginAuthorization(r.Context(), sessionId, issuerName)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
 
-	tmpl, err := template.New("login").Parse(string(templateContent))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// callbackHandler is the OIDC redirect_uri target: it exchanges the
+// authorization code for tokens, verifies the ID token and stores the
+// resulting session.
+func callbackHandler(w http.ResponseWriter, r *http.Request) {
+	if errParam := r.URL.Query().Get("error"); errParam != "" {
+		http.Error(w, fmt.Sprintf("authorization failed: %s", errParam), http.StatusBadRequest)
 		return
 	}
 
-	data := struct {
-		SessionId            string
-		AllowedMobileNumbers []string
-	}{
-		SessionId:            sessionId,
-		AllowedMobileNumbers: GetAllowedMobileNumbers(),
-	}
-
-	err = tmpl.Execute(w, data)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-	}
-}
-
-func loginHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	state := r.URL.Query().Get("state")
+	code := r.URL.Query().Get("code")
+	if state == "" || code == "" {
+		http.Error(w, "state and code are required", http.StatusBadRequest)
 		return
 	}
 
-	sessionId := r.FormValue("sessionId")
-	phoneNumber := r.FormValue("phoneNumber")
-
-	if sessionId == "" || phoneNumber == "" {
-		http.Error(w, "sessionId and phoneNumber are required", http.StatusBadRequest)
+	if _, err := authMiddleware.completeAuthorization(r.Context(), state, code); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	authMiddleware.AddSession(sessionId, phoneNumber)
-
-	// Read template from embedded filesystem
 	templateContent, err := staticFiles.ReadFile("static/login_successful.html")
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
 	tmpl, err := template.New("success").Parse(string(templateContent))
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	err = tmpl.Execute(w, nil)
-	if err != nil {
+	if err := tmpl.Execute(w, nil); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/fi-mcp-server/datasource"
+	"example.com/fi-mcp-server/notifiers"
+	"example.com/fi-mcp-server/oidc"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// notifierStore, notifierDispatcher, mcpResourceChannel and watcher back
+// the create/list/delete_notification_subscription tools, the financial
+// document resources and the background polling that feeds them both;
+// they're assembled once in init().
+var (
+	notifierStore      notifiers.Store
+	notifierDispatcher *notifiers.Dispatcher
+	mcpResourceChannel *notifiers.MCPResourceChannel
+	watcher            *sessionWatcher
+)
+
+// buildNotifierChannelsFromEnv wires up the delivery channels subscriptions
+// can target. mcp_resource is always available; smtp/webhook only appear
+// once their configuration is present, so an unconfigured channel fails
+// loudly at subscribe time rather than silently dropping alerts.
+func buildNotifierChannelsFromEnv() map[string]notifiers.Channel {
+	mcpResourceChannel = notifiers.NewMCPResourceChannel()
+	mcpResourceChannel.OnAlert(notifyResourceUpdated)
+
+	channels := map[string]notifiers.Channel{
+		"mcp_resource": mcpResourceChannel,
+	}
+	if secret := os.Getenv("NOTIFIERS_WEBHOOK_SECRET"); secret != "" {
+		channels["webhook"] = notifiers.NewWebhookChannel(secret)
+	}
+	if addr := os.Getenv("NOTIFIERS_SMTP_ADDR"); addr != "" {
+		host := envOrDefault("NOTIFIERS_SMTP_HOST", strings.Split(addr, ":")[0])
+		channels["smtp"] = notifiers.NewSMTPChannel(
+			addr,
+			envOrDefault("NOTIFIERS_SMTP_FROM", "alerts@fi.money"),
+			os.Getenv("NOTIFIERS_SMTP_USERNAME"),
+			os.Getenv("NOTIFIERS_SMTP_PASSWORD"),
+			host,
+		)
+	}
+	return channels
+}
+
+// requestArgs returns the tool-call arguments as a map, or nil if absent.
+func requestArgs(req mcp.CallToolRequest) map[string]any {
+	args, _ := req.Params.Arguments.(map[string]any)
+	return args
+}
+
+func argString(req mcp.CallToolRequest, key string) string {
+	v, _ := requestArgs(req)[key].(string)
+	return v
+}
+
+func createSubscriptionHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	userID, _ := ctx.Value(identityContextKey).(string)
+	topic := argString(req, "topic")
+	if _, ok := notifiers.Rules[topic]; !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown topic %q", topic)), nil
+	}
+
+	channel := argString(req, "channel")
+	if notifierDispatcher != nil && !notifierDispatcher.HasChannel(channel) {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown channel %q", channel)), nil
+	}
+
+	filter := map[string]string{}
+	if raw, ok := requestArgs(req)["filter"].(map[string]any); ok {
+		for k, v := range raw {
+			filter[k] = fmt.Sprintf("%v", v)
+		}
+	}
+
+	id, err := oidc.GenerateState()
+	if err != nil {
+		return mcp.NewToolResultError("failed to generate subscription id"), nil
+	}
+	sub := &notifiers.Subscription{
+		ID:      id,
+		UserID:  userID,
+		Topic:   topic,
+		Channel: channel,
+		Target:  argString(req, "target"),
+		Filter:  filter,
+	}
+	if err := notifierStore.Create(sub); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	body, err := json.Marshal(sub)
+	if err != nil {
+		return mcp.NewToolResultError("failed to encode subscription"), nil
+	}
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func listSubscriptionsHandler(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	userID, _ := ctx.Value(identityContextKey).(string)
+	body, err := json.Marshal(notifierStore.List(userID))
+	if err != nil {
+		return mcp.NewToolResultError("failed to list subscriptions"), nil
+	}
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+func deleteSubscriptionHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	userID, _ := ctx.Value(identityContextKey).(string)
+	id := argString(req, "subscription_id")
+	sub, ok := notifierStore.Get(id)
+	if !ok || sub.UserID != userID {
+		return mcp.NewToolResultError("subscription not found"), nil
+	}
+	if err := notifierStore.Delete(id); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(`{"status":"deleted"}`), nil
+}
+
+// topicToTool maps a notifiers topic to the tool whose response the rule
+// behind it diffs.
+var topicToTool = map[string]string{
+	notifiers.TopicNetWorthDroppedPct: datasource.ToolFetchNetWorth,
+	notifiers.TopicNewCreditInquiry:   datasource.ToolFetchCreditReport,
+	notifiers.TopicMFTransactionAbove: datasource.ToolFetchMFTransactions,
+	notifiers.TopicEPFCreditReceived:  datasource.ToolFetchEPFDetails,
+}
+
+// resourceForTopic maps a notifiers topic to the resource artifact that
+// changed when it fires, so an mcp_resource subscription can push a
+// notifications/resources/updated event instead of leaving the client to
+// poll fetch_* tools for changes.
+var resourceForTopic = map[string]string{
+	notifiers.TopicNetWorthDroppedPct: "net_worth",
+	notifiers.TopicNewCreditInquiry:   "credit_report",
+	notifiers.TopicMFTransactionAbove: "mf_transactions",
+	notifiers.TopicEPFCreditReceived:  "epf_details",
+}
+
+// notifyResourceUpdated is mcpResourceChannel's OnAlert callback. It is a
+// no-op once userID's session has logged out or was never watched, the
+// same way the underlying polling stops.
+func notifyResourceUpdated(userID, topic string) {
+	artifact, ok := resourceForTopic[topic]
+	if !ok || mcpServer == nil || watcher == nil {
+		return
+	}
+	sessionID, ok := watcher.SessionID(userID)
+	if !ok {
+		return
+	}
+	if err := mcpServer.SendNotificationToSpecificClient(sessionID, "notifications/resources/updated", map[string]any{
+		"uri": resourceURI(userID, artifact),
+	}); err != nil {
+		log.Printf("notifier resource update: notifying %s: %v", userID, err)
+	}
+}
+
+// toolFetchByName reuses the same Fetch bindings ToolList registers tools
+// with, so the watcher reads data exactly the way a tool call would.
+var toolFetchByName = func() map[string]func(datasource.DataSource, context.Context, string) ([]byte, error) {
+	m := make(map[string]func(datasource.DataSource, context.Context, string) ([]byte, error))
+	for _, tool := range ToolList {
+		if tool.Fetch != nil {
+			m[tool.Name] = tool.Fetch
+		}
+	}
+	return m
+}()
+
+// watchInterval is how often sessionWatcher re-polls the DataSource on
+// behalf of an authenticated session.
+const watchInterval = 5 * time.Minute
+
+// sessionWatcher runs one background goroutine per authenticated MCP
+// session, periodically re-invoking the DataSource for every topic that
+// session's user has a subscription for, diffing against the last
+// observed response, and dispatching alerts for whatever rules fire.
+type sessionWatcher struct {
+	mu            sync.Mutex
+	active        map[string]chan struct{}
+	sessionByUser map[string]string
+}
+
+func newSessionWatcher() *sessionWatcher {
+	return &sessionWatcher{
+		active:        make(map[string]chan struct{}),
+		sessionByUser: make(map[string]string),
+	}
+}
+
+// Start begins polling for sessionId/userID unless it's already running.
+func (w *sessionWatcher) Start(sessionId, userID string, source datasource.DataSource, dispatcher *notifiers.Dispatcher, store notifiers.Store) {
+	w.mu.Lock()
+	if _, ok := w.active[sessionId]; ok {
+		w.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	w.active[sessionId] = stop
+	w.sessionByUser[userID] = sessionId
+	w.mu.Unlock()
+
+	go w.run(userID, source, dispatcher, store, stop)
+}
+
+// Stop ends polling for sessionId, if it was running.
+func (w *sessionWatcher) Stop(sessionId string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if stop, ok := w.active[sessionId]; ok {
+		close(stop)
+		delete(w.active, sessionId)
+	}
+	for userID, sid := range w.sessionByUser {
+		if sid == sessionId {
+			delete(w.sessionByUser, userID)
+			break
+		}
+	}
+}
+
+// SessionID returns the MCP session currently watching userID's data, if
+// any, so notifyResourceUpdated knows which session to push a
+// notifications/resources/updated event to.
+func (w *sessionWatcher) SessionID(userID string) (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sid, ok := w.sessionByUser[userID]
+	return sid, ok
+}
+
+func (w *sessionWatcher) run(userID string, source datasource.DataSource, dispatcher *notifiers.Dispatcher, store notifiers.Store, stop chan struct{}) {
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	previous := make(map[string][]byte)
+	ctx := context.Background()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, sub := range store.List(userID) {
+				toolName, ok := topicToTool[sub.Topic]
+				if !ok {
+					continue
+				}
+				fetch, ok := toolFetchByName[toolName]
+				if !ok {
+					continue
+				}
+				current, err := fetch(source, ctx, userID)
+				if err != nil {
+					log.Printf("notifier watcher: fetching %s for %s: %v", toolName, userID, err)
+					continue
+				}
+				prev, seen := previous[toolName]
+				previous[toolName] = current
+				if !seen {
+					continue
+				}
+				if err := dispatcher.Diff(ctx, userID, sub.Topic, prev, current); err != nil {
+					log.Printf("notifier watcher: dispatching %s for %s: %v", sub.Topic, userID, err)
+				}
+			}
+		}
+	}
+}
+
+// notifiersOpenAPISpec documents the admin endpoints below.
+const notifiersOpenAPISpec = `{
+  "openapi": "3.0.0",
+  "info": {"title": "Fi MCP Notifiers Admin API", "version": "1.0.0"},
+  "paths": {
+    "/notifiers/subscriptions": {
+      "get": {
+        "summary": "List notification subscriptions for a user",
+        "parameters": [{"name": "userID", "in": "query", "required": true, "schema": {"type": "string"}}],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`
+
+// notifiersAdminHandler serves the /notifiers/ admin endpoints, which
+// expose subscription Target values (the user's email address or webhook
+// URL). It fails closed: requests must carry NOTIFIERS_ADMIN_TOKEN as a
+// bearer token, and if the env var isn't set the endpoints refuse every
+// request rather than becoming unauthenticated.
+func notifiersAdminHandler(w http.ResponseWriter, r *http.Request) {
+	token := os.Getenv("NOTIFIERS_ADMIN_TOKEN")
+	if token == "" || r.Header.Get("Authorization") != "Bearer "+token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/notifiers/openapi.json":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, notifiersOpenAPISpec)
+	case "/notifiers/subscriptions":
+		userID := r.URL.Query().Get("userID")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(notifierStore.List(userID))
+	default:
+		http.NotFound(w, r)
+	}
+}
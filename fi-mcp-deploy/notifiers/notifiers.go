@@ -0,0 +1,427 @@
+// Package notifiers lets agents built on top of the MCP server subscribe to
+// material changes in a user's financial data instead of only pulling it on
+// demand. A Subscription pairs a rule-engine Topic with a delivery Channel;
+// Dispatcher.Diff evaluates the topic's Rule against two successive tool
+// responses and, on a match, routes the resulting Alert through the
+// subscription's Channel.
+package notifiers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Topic names accepted by Subscription.Topic; each one must have a
+// corresponding entry in Rules.
+const (
+	TopicNetWorthDroppedPct = "net_worth_dropped_more_than_pct"
+	TopicNewCreditInquiry   = "new_credit_inquiry"
+	TopicMFTransactionAbove = "mf_transaction_above_amount"
+	TopicEPFCreditReceived  = "epf_credit_received"
+)
+
+// Subscription is one user's standing request to be alerted when Topic
+// fires for the tool response behind Channel/Target. Filter carries
+// rule-specific thresholds such as {"pct": "10"} or {"amount": "50000"}.
+type Subscription struct {
+	ID      string
+	UserID  string
+	Topic   string
+	Channel string
+	Target  string
+	Filter  map[string]string
+}
+
+// Alert is what a Rule produces when it fires, and what a Channel delivers.
+type Alert struct {
+	SubscriptionID string
+	UserID         string
+	Topic          string
+	Message        string
+	Data           json.RawMessage
+	FiredAt        time.Time
+}
+
+// Store persists subscriptions.
+type Store interface {
+	Create(sub *Subscription) error
+	Get(id string) (*Subscription, bool)
+	List(userID string) []*Subscription
+	Delete(id string) error
+}
+
+// InMemoryStore is a mutex-guarded Store good enough for a single running
+// instance; it does not survive a restart.
+type InMemoryStore struct {
+	mu   sync.RWMutex
+	subs map[string]*Subscription
+}
+
+// NewInMemoryStore returns an empty InMemoryStore.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{subs: make(map[string]*Subscription)}
+}
+
+func (s *InMemoryStore) Create(sub *Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subs[sub.ID] = sub
+	return nil
+}
+
+func (s *InMemoryStore) Get(id string) (*Subscription, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sub, ok := s.subs[id]
+	return sub, ok
+}
+
+func (s *InMemoryStore) List(userID string) []*Subscription {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []*Subscription
+	for _, sub := range s.subs {
+		if sub.UserID == userID {
+			out = append(out, sub)
+		}
+	}
+	return out
+}
+
+func (s *InMemoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[id]; !ok {
+		return fmt.Errorf("notifiers: unknown subscription %q", id)
+	}
+	delete(s.subs, id)
+	return nil
+}
+
+// Rule evaluates a diff between two successive tool responses for one
+// topic and returns the Alert to dispatch, or nil if it didn't fire.
+type Rule func(sub *Subscription, previous, current []byte) (*Alert, error)
+
+// Rules maps topic name to the Rule that evaluates it.
+var Rules = map[string]Rule{
+	TopicNetWorthDroppedPct: netWorthDroppedPct,
+	TopicNewCreditInquiry:   newCreditInquiry,
+	TopicMFTransactionAbove: mfTransactionAbove,
+	TopicEPFCreditReceived:  epfCreditReceived,
+}
+
+func unmarshalMap(raw []byte) (map[string]interface{}, error) {
+	var m map[string]interface{}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, fmt.Errorf("notifiers: parsing tool response: %w", err)
+	}
+	return m, nil
+}
+
+func extractFloat(raw []byte, key string) (float64, bool) {
+	m, err := unmarshalMap(raw)
+	if err != nil {
+		return 0, false
+	}
+	switch v := m[key].(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func extractArray(raw []byte, key string) []interface{} {
+	m, err := unmarshalMap(raw)
+	if err != nil {
+		return nil
+	}
+	arr, _ := m[key].([]interface{})
+	return arr
+}
+
+// netWorthDroppedPct fires when the "totalNetWorthValue" field of current
+// is more than sub.Filter["pct"] percent lower than in previous (default
+// 5%, if the filter is missing or not numeric).
+func netWorthDroppedPct(sub *Subscription, previous, current []byte) (*Alert, error) {
+	prevVal, ok := extractFloat(previous, "totalNetWorthValue")
+	if !ok || prevVal <= 0 {
+		return nil, nil
+	}
+	currVal, ok := extractFloat(current, "totalNetWorthValue")
+	if !ok {
+		return nil, nil
+	}
+
+	threshold, err := strconv.ParseFloat(sub.Filter["pct"], 64)
+	if err != nil {
+		threshold = 5
+	}
+	dropPct := (prevVal - currVal) / prevVal * 100
+	if dropPct <= threshold {
+		return nil, nil
+	}
+
+	return &Alert{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		Topic:          TopicNetWorthDroppedPct,
+		Message:        fmt.Sprintf("net worth dropped %.1f%%, from %.2f to %.2f", dropPct, prevVal, currVal),
+		Data:           current,
+		FiredAt:        time.Now(),
+	}, nil
+}
+
+// newCreditInquiry fires when current's "recentInquiries" array is longer
+// than previous's.
+func newCreditInquiry(sub *Subscription, previous, current []byte) (*Alert, error) {
+	prevCount := len(extractArray(previous, "recentInquiries"))
+	currCount := len(extractArray(current, "recentInquiries"))
+	if currCount <= prevCount {
+		return nil, nil
+	}
+
+	return &Alert{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		Topic:          TopicNewCreditInquiry,
+		Message:        fmt.Sprintf("%d new credit inquiry(ies) since last check", currCount-prevCount),
+		Data:           current,
+		FiredAt:        time.Now(),
+	}, nil
+}
+
+// mfTransactionAbove fires when current's "transactions" array contains a
+// transaction, absent from previous, whose "transactionAmount" exceeds
+// sub.Filter["amount"].
+func mfTransactionAbove(sub *Subscription, previous, current []byte) (*Alert, error) {
+	threshold, err := strconv.ParseFloat(sub.Filter["amount"], 64)
+	if err != nil {
+		return nil, fmt.Errorf("notifiers: subscription %q missing numeric amount filter", sub.ID)
+	}
+
+	seen := make(map[string]bool)
+	for _, txn := range extractArray(previous, "transactions") {
+		if m, ok := txn.(map[string]interface{}); ok {
+			seen[fmt.Sprintf("%v", m["transactionId"])] = true
+		}
+	}
+
+	for _, txn := range extractArray(current, "transactions") {
+		m, ok := txn.(map[string]interface{})
+		if !ok || seen[fmt.Sprintf("%v", m["transactionId"])] {
+			continue
+		}
+		amount, _ := m["transactionAmount"].(float64)
+		if amount > threshold {
+			return &Alert{
+				SubscriptionID: sub.ID,
+				UserID:         sub.UserID,
+				Topic:          TopicMFTransactionAbove,
+				Message:        fmt.Sprintf("new mutual fund transaction of %.2f exceeds threshold %.2f", amount, threshold),
+				Data:           current,
+				FiredAt:        time.Now(),
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// epfCreditReceived fires when current's "totalContribution" field is
+// higher than in previous.
+func epfCreditReceived(sub *Subscription, previous, current []byte) (*Alert, error) {
+	prevTotal, _ := extractFloat(previous, "totalContribution")
+	currTotal, ok := extractFloat(current, "totalContribution")
+	if !ok || currTotal <= prevTotal {
+		return nil, nil
+	}
+
+	return &Alert{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		Topic:          TopicEPFCreditReceived,
+		Message:        fmt.Sprintf("EPF credited: contribution total rose from %.2f to %.2f", prevTotal, currTotal),
+		Data:           current,
+		FiredAt:        time.Now(),
+	}, nil
+}
+
+// Channel delivers an Alert somewhere outside the rules engine.
+type Channel interface {
+	Deliver(ctx context.Context, sub *Subscription, alert *Alert) error
+}
+
+// SMTPChannel delivers alerts as plain-text email through an SMTP relay.
+type SMTPChannel struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+// NewSMTPChannel returns an SMTPChannel authenticating with PLAIN auth.
+func NewSMTPChannel(addr, from, username, password, host string) *SMTPChannel {
+	return &SMTPChannel{Addr: addr, From: from, Auth: smtp.PlainAuth("", username, password, host)}
+}
+
+func (c *SMTPChannel) Deliver(_ context.Context, sub *Subscription, alert *Alert) error {
+	msg := fmt.Sprintf("Subject: Fi Money alert: %s\r\n\r\n%s\r\n", alert.Topic, alert.Message)
+	if err := smtp.SendMail(c.Addr, c.Auth, c.From, []string{sub.Target}, []byte(msg)); err != nil {
+		return fmt.Errorf("notifiers: sending email: %w", err)
+	}
+	return nil
+}
+
+// WebhookChannel POSTs the alert as JSON to sub.Target, signing the body
+// with HMAC-SHA256 under Secret (carried in the X-Fi-Signature header) so
+// receivers can verify it came from this server.
+type WebhookChannel struct {
+	Secret string
+	Client *http.Client
+}
+
+// NewWebhookChannel returns a WebhookChannel signing bodies with secret.
+func NewWebhookChannel(secret string) *WebhookChannel {
+	return &WebhookChannel{Secret: secret, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (c *WebhookChannel) Deliver(ctx context.Context, sub *Subscription, alert *Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return fmt.Errorf("notifiers: marshaling alert: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(c.Secret))
+	mac.Write(body)
+	sig := hex.EncodeToString(mac.Sum(nil))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Fi-Signature", sig)
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notifiers: delivering webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("notifiers: webhook target returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// MCPResourceChannel buffers alerts per user in-process instead of
+// delivering them externally, so they can be surfaced as MCP resources
+// rather than pushed to a third party.
+type MCPResourceChannel struct {
+	mu      sync.Mutex
+	alerts  map[string][]*Alert
+	onAlert func(userID, topic string)
+}
+
+// NewMCPResourceChannel returns an empty MCPResourceChannel.
+func NewMCPResourceChannel() *MCPResourceChannel {
+	return &MCPResourceChannel{alerts: make(map[string][]*Alert)}
+}
+
+// OnAlert registers fn to run, outside the channel's lock, every time an
+// alert is buffered. The server uses this to push a
+// notifications/resources/updated event to the subscription's owning
+// session instead of making the client poll for new alerts.
+func (c *MCPResourceChannel) OnAlert(fn func(userID, topic string)) {
+	c.mu.Lock()
+	c.onAlert = fn
+	c.mu.Unlock()
+}
+
+func (c *MCPResourceChannel) Deliver(_ context.Context, sub *Subscription, alert *Alert) error {
+	c.mu.Lock()
+	c.alerts[sub.UserID] = append(c.alerts[sub.UserID], alert)
+	onAlert := c.onAlert
+	c.mu.Unlock()
+
+	if onAlert != nil {
+		onAlert(sub.UserID, sub.Topic)
+	}
+	return nil
+}
+
+// Alerts returns every alert buffered for userID, oldest first.
+func (c *MCPResourceChannel) Alerts(userID string) []*Alert {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]*Alert, len(c.alerts[userID]))
+	copy(out, c.alerts[userID])
+	return out
+}
+
+// Dispatcher evaluates subscriptions against successive tool responses and
+// routes any resulting Alert through the matching Channel.
+type Dispatcher struct {
+	store    Store
+	channels map[string]Channel
+}
+
+// NewDispatcher returns a Dispatcher reading subscriptions from store and
+// delivering through channels, keyed by Subscription.Channel.
+func NewDispatcher(store Store, channels map[string]Channel) *Dispatcher {
+	return &Dispatcher{store: store, channels: channels}
+}
+
+// HasChannel reports whether name is a registered delivery channel, so
+// callers can reject a subscription with an unknown Channel at create time
+// instead of only discovering it once Diff fires.
+func (d *Dispatcher) HasChannel(name string) bool {
+	_, ok := d.channels[name]
+	return ok
+}
+
+// Diff evaluates every userID subscription for topic against previous and
+// current, dispatching an Alert through its channel for each one that
+// fires. Errors from individual subscriptions are collected, not fatal.
+func (d *Dispatcher) Diff(ctx context.Context, userID, topic string, previous, current []byte) error {
+	rule, ok := Rules[topic]
+	if !ok {
+		return fmt.Errorf("notifiers: no rule registered for topic %q", topic)
+	}
+
+	var errs []error
+	for _, sub := range d.store.List(userID) {
+		if sub.Topic != topic {
+			continue
+		}
+		alert, err := rule(sub, previous, current)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if alert == nil {
+			continue
+		}
+		ch, ok := d.channels[sub.Channel]
+		if !ok {
+			errs = append(errs, fmt.Errorf("notifiers: unknown channel %q", sub.Channel))
+			continue
+		}
+		if err := ch.Deliver(ctx, sub, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,193 @@
+package notifiers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestInMemoryStoreCRUD(t *testing.T) {
+	s := NewInMemoryStore()
+	sub := &Subscription{ID: "sub-1", UserID: "u1", Topic: TopicNetWorthDroppedPct, Channel: "mcp_resource"}
+
+	if err := s.Create(sub); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	if got, ok := s.Get("sub-1"); !ok || got != sub {
+		t.Errorf("Get = %v, %v; want %v, true", got, ok, sub)
+	}
+	if got := s.List("u1"); len(got) != 1 {
+		t.Errorf("List(u1) = %v, want 1 entry", got)
+	}
+	if err := s.Delete("sub-1"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if err := s.Delete("sub-1"); err == nil {
+		t.Error("expected error deleting already-deleted subscription")
+	}
+}
+
+func TestNetWorthDroppedPct(t *testing.T) {
+	sub := &Subscription{ID: "sub-1", UserID: "u1", Filter: map[string]string{"pct": "10"}}
+
+	previous := []byte(`{"totalNetWorthValue": 100000}`)
+	current := []byte(`{"totalNetWorthValue": 85000}`)
+	alert, err := netWorthDroppedPct(sub, previous, current)
+	if err != nil {
+		t.Fatalf("netWorthDroppedPct returned error: %v", err)
+	}
+	if alert == nil {
+		t.Fatal("expected an alert for a 15% drop with a 10% threshold")
+	}
+
+	current = []byte(`{"totalNetWorthValue": 95000}`)
+	alert, err = netWorthDroppedPct(sub, previous, current)
+	if err != nil {
+		t.Fatalf("netWorthDroppedPct returned error: %v", err)
+	}
+	if alert != nil {
+		t.Errorf("expected no alert for a 5%% drop with a 10%% threshold, got %+v", alert)
+	}
+}
+
+func TestNewCreditInquiry(t *testing.T) {
+	sub := &Subscription{ID: "sub-1", UserID: "u1"}
+	previous := []byte(`{"recentInquiries": [{}]}`)
+	current := []byte(`{"recentInquiries": [{}, {}]}`)
+
+	alert, err := newCreditInquiry(sub, previous, current)
+	if err != nil {
+		t.Fatalf("newCreditInquiry returned error: %v", err)
+	}
+	if alert == nil {
+		t.Fatal("expected an alert when a new inquiry appears")
+	}
+
+	alert, err = newCreditInquiry(sub, current, current)
+	if err != nil {
+		t.Fatalf("newCreditInquiry returned error: %v", err)
+	}
+	if alert != nil {
+		t.Errorf("expected no alert with no new inquiries, got %+v", alert)
+	}
+}
+
+func TestMFTransactionAbove(t *testing.T) {
+	sub := &Subscription{ID: "sub-1", UserID: "u1", Filter: map[string]string{"amount": "50000"}}
+	previous := []byte(`{"transactions": [{"transactionId": "t1", "transactionAmount": 1000}]}`)
+	current := []byte(`{"transactions": [{"transactionId": "t1", "transactionAmount": 1000}, {"transactionId": "t2", "transactionAmount": 75000}]}`)
+
+	alert, err := mfTransactionAbove(sub, previous, current)
+	if err != nil {
+		t.Fatalf("mfTransactionAbove returned error: %v", err)
+	}
+	if alert == nil {
+		t.Fatal("expected an alert for a new transaction above the threshold")
+	}
+}
+
+func TestEPFCreditReceived(t *testing.T) {
+	sub := &Subscription{ID: "sub-1", UserID: "u1"}
+	previous := []byte(`{"totalContribution": 100000}`)
+	current := []byte(`{"totalContribution": 112000}`)
+
+	alert, err := epfCreditReceived(sub, previous, current)
+	if err != nil {
+		t.Fatalf("epfCreditReceived returned error: %v", err)
+	}
+	if alert == nil {
+		t.Fatal("expected an alert when contributions increase")
+	}
+}
+
+func TestWebhookChannelSignsPayload(t *testing.T) {
+	var gotSig string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSig = r.Header.Get("X-Fi-Signature")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	ch := NewWebhookChannel("shh")
+	sub := &Subscription{ID: "sub-1", UserID: "u1", Target: srv.URL}
+	alert := &Alert{SubscriptionID: "sub-1", UserID: "u1", Topic: TopicNetWorthDroppedPct, Message: "dropped"}
+
+	if err := ch.Deliver(context.Background(), sub, alert); err != nil {
+		t.Fatalf("Deliver returned error: %v", err)
+	}
+	if gotSig == "" {
+		t.Error("expected a non-empty X-Fi-Signature header")
+	}
+}
+
+func TestMCPResourceChannelBuffersPerUser(t *testing.T) {
+	ch := NewMCPResourceChannel()
+	sub := &Subscription{ID: "sub-1", UserID: "u1"}
+	alert := &Alert{SubscriptionID: "sub-1", UserID: "u1", Topic: TopicEPFCreditReceived}
+
+	if err := ch.Deliver(context.Background(), sub, alert); err != nil {
+		t.Fatalf("Deliver returned error: %v", err)
+	}
+	if got := ch.Alerts("u1"); len(got) != 1 {
+		t.Errorf("Alerts(u1) = %v, want 1 entry", got)
+	}
+	if got := ch.Alerts("u2"); len(got) != 0 {
+		t.Errorf("Alerts(u2) = %v, want 0 entries", got)
+	}
+}
+
+func TestMCPResourceChannelOnAlertRunsAfterBuffering(t *testing.T) {
+	ch := NewMCPResourceChannel()
+	var gotUserID, gotTopic string
+	ch.OnAlert(func(userID, topic string) {
+		gotUserID, gotTopic = userID, topic
+		// The alert must already be visible to Alerts by the time OnAlert runs.
+		if got := ch.Alerts(userID); len(got) != 1 {
+			t.Errorf("Alerts(%s) inside OnAlert = %v, want 1 entry", userID, got)
+		}
+	})
+
+	sub := &Subscription{ID: "sub-1", UserID: "u1", Topic: TopicEPFCreditReceived}
+	alert := &Alert{SubscriptionID: "sub-1", UserID: "u1", Topic: TopicEPFCreditReceived}
+	if err := ch.Deliver(context.Background(), sub, alert); err != nil {
+		t.Fatalf("Deliver returned error: %v", err)
+	}
+	if gotUserID != "u1" || gotTopic != TopicEPFCreditReceived {
+		t.Errorf("OnAlert called with (%q, %q), want (u1, %q)", gotUserID, gotTopic, TopicEPFCreditReceived)
+	}
+}
+
+func TestDispatcherDiffDeliversToMatchingChannel(t *testing.T) {
+	store := NewInMemoryStore()
+	store.Create(&Subscription{
+		ID:      "sub-1",
+		UserID:  "u1",
+		Topic:   TopicNetWorthDroppedPct,
+		Channel: "mcp_resource",
+		Filter:  map[string]string{"pct": "5"},
+	})
+
+	mcpChannel := NewMCPResourceChannel()
+	d := NewDispatcher(store, map[string]Channel{"mcp_resource": mcpChannel})
+
+	previous := []byte(`{"totalNetWorthValue": 100000}`)
+	current := []byte(`{"totalNetWorthValue": 80000}`)
+	if err := d.Diff(context.Background(), "u1", TopicNetWorthDroppedPct, previous, current); err != nil {
+		t.Fatalf("Diff returned error: %v", err)
+	}
+	if got := mcpChannel.Alerts("u1"); len(got) != 1 {
+		t.Errorf("Alerts(u1) = %v, want 1 entry", got)
+	}
+}
+
+func TestDispatcherHasChannel(t *testing.T) {
+	d := NewDispatcher(NewInMemoryStore(), map[string]Channel{"mcp_resource": NewMCPResourceChannel()})
+
+	if !d.HasChannel("mcp_resource") {
+		t.Error(`HasChannel("mcp_resource") = false, want true`)
+	}
+	if d.HasChannel("webhook") {
+		t.Error(`HasChannel("webhook") = true, want false`)
+	}
+}
@@ -0,0 +1,410 @@
+// Package oidc implements a minimal OAuth 2.0 authorization-code flow with
+// PKCE against one or more externally configured OpenID Connect issuers
+// (Google, Fi's own IdP, or anything else that publishes
+// .well-known/openid-configuration). It intentionally avoids pulling in a
+// full OIDC client library: the server only ever needs to build an
+// authorization URL, exchange/refresh a code for tokens and verify the
+// resulting ID token against the issuer's JWKS.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Issuer holds the static configuration for one OIDC provider.
+type Issuer struct {
+	Name         string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	mu        sync.RWMutex
+	discovery *discoveryDocument
+	jwks      *jwks
+	jwksAt    time.Time
+}
+
+type discoveryDocument struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	Issuer                string `json:"issuer"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+const jwksCacheTTL = 10 * time.Minute
+
+// Manager keeps track of every configured issuer, keyed by the name used in
+// the "issuer" query parameter of /authorize.
+type Manager struct {
+	mu      sync.RWMutex
+	issuers map[string]*Issuer
+	client  *http.Client
+}
+
+// NewManager returns an empty issuer manager. Call RegisterIssuer for every
+// issuer read from configuration before serving traffic.
+func NewManager() *Manager {
+	return &Manager{
+		issuers: make(map[string]*Issuer),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// RegisterIssuer adds an issuer under its Name.
+func (m *Manager) RegisterIssuer(iss *Issuer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.issuers[iss.Name] = iss
+}
+
+// Issuer looks up a previously registered issuer by name.
+func (m *Manager) Issuer(name string) (*Issuer, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	iss, ok := m.issuers[name]
+	return iss, ok
+}
+
+func (m *Manager) discover(ctx context.Context, iss *Issuer) (*discoveryDocument, error) {
+	iss.mu.RLock()
+	if iss.discovery != nil {
+		d := iss.discovery
+		iss.mu.RUnlock()
+		return d, nil
+	}
+	iss.mu.RUnlock()
+
+	wellKnown := strings.TrimSuffix(iss.IssuerURL, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery document returned %d", resp.StatusCode)
+	}
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decoding discovery document: %w", err)
+	}
+
+	iss.mu.Lock()
+	iss.discovery = &doc
+	iss.mu.Unlock()
+	return &doc, nil
+}
+
+// AuthCodeURL builds the /authorize redirect target for iss, embedding the
+// PKCE challenge and the nonce/state the callback must see again.
+func (m *Manager) AuthCodeURL(ctx context.Context, iss *Issuer, state, nonce, codeChallenge string) (string, error) {
+	doc, err := m.discover(ctx, iss)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {iss.ClientID},
+		"redirect_uri":          {iss.RedirectURL},
+		"scope":                 {strings.Join(iss.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+	return doc.AuthorizationEndpoint + "?" + q.Encode(), nil
+}
+
+// TokenResponse is the subset of the token endpoint response this package
+// cares about.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope"`
+}
+
+// ExchangeCode trades an authorization code and its PKCE verifier for a
+// token set at iss's token endpoint.
+func (m *Manager) ExchangeCode(ctx context.Context, iss *Issuer, code, codeVerifier string) (*TokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {iss.RedirectURL},
+		"client_id":     {iss.ClientID},
+		"client_secret": {iss.ClientSecret},
+		"code_verifier": {codeVerifier},
+	}
+	return m.postForm(ctx, iss, form)
+}
+
+// RefreshToken trades a refresh token for a new token set.
+func (m *Manager) RefreshToken(ctx context.Context, iss *Issuer, refreshToken string) (*TokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {iss.ClientID},
+		"client_secret": {iss.ClientSecret},
+	}
+	return m.postForm(ctx, iss, form)
+}
+
+func (m *Manager) postForm(ctx context.Context, iss *Issuer, form url.Values) (*TokenResponse, error) {
+	doc, err := m.discover(ctx, iss)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("calling token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, body)
+	}
+	var tok TokenResponse
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return nil, fmt.Errorf("decoding token response: %w", err)
+	}
+	return &tok, nil
+}
+
+func (m *Manager) fetchJWKS(ctx context.Context, iss *Issuer) (*jwks, error) {
+	iss.mu.RLock()
+	if iss.jwks != nil && time.Since(iss.jwksAt) < jwksCacheTTL {
+		keys := iss.jwks
+		iss.mu.RUnlock()
+		return keys, nil
+	}
+	iss.mu.RUnlock()
+
+	doc, err := m.discover(ctx, iss)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, doc.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks endpoint returned %d", resp.StatusCode)
+	}
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decoding jwks: %w", err)
+	}
+
+	iss.mu.Lock()
+	iss.jwks = &set
+	iss.jwksAt = time.Now()
+	iss.mu.Unlock()
+	return &set, nil
+}
+
+// Claims is the subset of ID token claims the rest of the server relies on.
+type Claims struct {
+	Subject     string `json:"sub"`
+	PhoneNumber string `json:"phone_number"`
+	Issuer      string `json:"iss"`
+	Audience    string `json:"-"`
+	Nonce       string `json:"nonce"`
+	Expiry      int64  `json:"exp"`
+}
+
+var (
+	// ErrTokenExpired is returned by VerifyIDToken when exp has passed.
+	ErrTokenExpired = errors.New("oidc: id token expired")
+	// ErrNonceMismatch is returned when the nonce in the token does not
+	// match the one generated for this login attempt.
+	ErrNonceMismatch = errors.New("oidc: nonce mismatch")
+)
+
+// VerifyIDToken validates the signature, issuer, audience, expiry and nonce
+// of a compact JWT ID token issued by iss, and returns its claims.
+func (m *Manager) VerifyIDToken(ctx context.Context, iss *Issuer, rawToken, wantNonce string) (*Claims, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("oidc: malformed id token")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("oidc: decoding header: %w", err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("oidc: parsing header: %w", err)
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("oidc: unsupported alg %q", header.Alg)
+	}
+
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("oidc: decoding payload: %w", err)
+	}
+	var raw struct {
+		Sub         string      `json:"sub"`
+		PhoneNumber string      `json:"phone_number"`
+		Iss         string      `json:"iss"`
+		Aud         interface{} `json:"aud"`
+		Nonce       string      `json:"nonce"`
+		Exp         int64       `json:"exp"`
+	}
+	if err := json.Unmarshal(payloadJSON, &raw); err != nil {
+		return nil, fmt.Errorf("oidc: parsing payload: %w", err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("oidc: decoding signature: %w", err)
+	}
+
+	set, err := m.fetchJWKS(ctx, iss)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := findKey(set, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, fmt.Errorf("oidc: signature verification failed: %w", err)
+	}
+
+	if raw.Iss != iss.IssuerURL && raw.Iss != strings.TrimSuffix(iss.IssuerURL, "/") {
+		return nil, fmt.Errorf("oidc: unexpected issuer %q", raw.Iss)
+	}
+	if !audienceContains(raw.Aud, iss.ClientID) {
+		return nil, fmt.Errorf("oidc: token not issued for this client")
+	}
+	if time.Now().Unix() >= raw.Exp {
+		return nil, ErrTokenExpired
+	}
+	if wantNonce != "" && raw.Nonce != wantNonce {
+		return nil, ErrNonceMismatch
+	}
+
+	return &Claims{
+		Subject:     raw.Sub,
+		PhoneNumber: raw.PhoneNumber,
+		Issuer:      raw.Iss,
+		Nonce:       raw.Nonce,
+		Expiry:      raw.Exp,
+	}, nil
+}
+
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func findKey(set *jwks, kid string) (*rsa.PublicKey, error) {
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (kid != "" && k.Kid != kid) {
+			continue
+		}
+		return rsaPublicKey(k)
+	}
+	return nil, fmt.Errorf("oidc: no matching jwks key for kid %q", kid)
+}
+
+func rsaPublicKey(k jwk) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: decoding modulus: %w", err)
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: decoding exponent: %w", err)
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
+
+// GenerateState returns a URL-safe random string suitable for the OAuth
+// "state" parameter or an ID token nonce.
+func GenerateState() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// NewPKCEVerifier returns a random code_verifier and its S256
+// code_challenge, per RFC 7636.
+func NewPKCEVerifier() (verifier, challenge string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(verifier))
+	challenge = base64.RawURLEncoding.EncodeToString(sum[:])
+	return verifier, challenge, nil
+}
@@ -0,0 +1,187 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// signToken builds a compact RS256 JWT signed with key for the given claims.
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header := map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"}
+	headerJSON, _ := json.Marshal(header)
+	payloadJSON, _ := json.Marshal(claims)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." +
+		base64.RawURLEncoding.EncodeToString(payloadJSON)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func newFakeIssuer(t *testing.T, key *rsa.PrivateKey, kid string) (*Manager, *Issuer, func()) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{
+			"issuer": %q,
+			"authorization_endpoint": "%s/authorize",
+			"token_endpoint": "%s/token",
+			"jwks_uri": "%s/jwks.json"
+		}`, srv.URL, srv.URL, srv.URL, srv.URL)
+	})
+	mux.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		n := base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes())
+		e := base64.RawURLEncoding.EncodeToString([]byte{1, 0, 1})
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"keys":[{"kty":"RSA","kid":%q,"alg":"RS256","n":%q,"e":%q}]}`, kid, n, e)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"at","refresh_token":"rt","id_token":"it","expires_in":3600,"token_type":"Bearer"}`)
+	})
+
+	m := NewManager()
+	iss := &Issuer{
+		Name:         "fake",
+		IssuerURL:    srv.URL,
+		ClientID:     "client-123",
+		ClientSecret: "secret",
+		RedirectURL:  "https://app.example.com/callback",
+		Scopes:       []string{"openid", "phone"},
+	}
+	m.RegisterIssuer(iss)
+	return m, iss, srv.Close
+}
+
+func TestVerifyIDToken_Valid(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	m, iss, closeSrv := newFakeIssuer(t, key, "kid-1")
+	defer closeSrv()
+
+	token := signToken(t, key, "kid-1", map[string]interface{}{
+		"iss":          iss.IssuerURL,
+		"aud":          iss.ClientID,
+		"sub":          "user-1",
+		"phone_number": "1111111111",
+		"nonce":        "expected-nonce",
+		"exp":          time.Now().Add(time.Hour).Unix(),
+	})
+
+	claims, err := m.VerifyIDToken(context.Background(), iss, token, "expected-nonce")
+	if err != nil {
+		t.Fatalf("VerifyIDToken returned error: %v", err)
+	}
+	if claims.PhoneNumber != "1111111111" {
+		t.Errorf("PhoneNumber = %q, want 1111111111", claims.PhoneNumber)
+	}
+}
+
+func TestVerifyIDToken_ExpiredToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	m, iss, closeSrv := newFakeIssuer(t, key, "kid-1")
+	defer closeSrv()
+
+	token := signToken(t, key, "kid-1", map[string]interface{}{
+		"iss":   iss.IssuerURL,
+		"aud":   iss.ClientID,
+		"sub":   "user-1",
+		"nonce": "n",
+		"exp":   time.Now().Add(-time.Hour).Unix(),
+	})
+
+	if _, err := m.VerifyIDToken(context.Background(), iss, token, "n"); err != ErrTokenExpired {
+		t.Errorf("expected ErrTokenExpired, got %v", err)
+	}
+}
+
+func TestVerifyIDToken_NonceMismatch(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	m, iss, closeSrv := newFakeIssuer(t, key, "kid-1")
+	defer closeSrv()
+
+	token := signToken(t, key, "kid-1", map[string]interface{}{
+		"iss":   iss.IssuerURL,
+		"aud":   iss.ClientID,
+		"sub":   "user-1",
+		"nonce": "actual-nonce",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+
+	if _, err := m.VerifyIDToken(context.Background(), iss, token, "expected-nonce"); err != ErrNonceMismatch {
+		t.Errorf("expected ErrNonceMismatch, got %v", err)
+	}
+}
+
+func TestVerifyIDToken_WrongAudience(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	m, iss, closeSrv := newFakeIssuer(t, key, "kid-1")
+	defer closeSrv()
+
+	token := signToken(t, key, "kid-1", map[string]interface{}{
+		"iss":   iss.IssuerURL,
+		"aud":   "someone-else",
+		"sub":   "user-1",
+		"nonce": "n",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+
+	if _, err := m.VerifyIDToken(context.Background(), iss, token, "n"); err == nil {
+		t.Error("expected error for wrong audience, got nil")
+	}
+}
+
+func TestExchangeCodeAndRefresh(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	m, iss, closeSrv := newFakeIssuer(t, key, "kid-1")
+	defer closeSrv()
+
+	tok, err := m.ExchangeCode(context.Background(), iss, "auth-code", "verifier")
+	if err != nil {
+		t.Fatalf("ExchangeCode returned error: %v", err)
+	}
+	if tok.AccessToken != "at" || tok.RefreshToken != "rt" {
+		t.Errorf("unexpected token response: %+v", tok)
+	}
+
+	refreshed, err := m.RefreshToken(context.Background(), iss, tok.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshToken returned error: %v", err)
+	}
+	if refreshed.AccessToken != "at" {
+		t.Errorf("unexpected refreshed token: %+v", refreshed)
+	}
+}
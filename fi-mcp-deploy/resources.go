@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"example.com/fi-mcp-server/datasource"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// resourceSchemaBase namespaces the $schema identifier every resource's
+// JSON content declares, so a client can route or validate on shape
+// without depending on this server's Go types.
+const resourceSchemaBase = "https://fi.money/schemas"
+
+// resourceArtifact is one financial document exposed as an MCP resource
+// instead of only a fetch_* tool result, so a client can read or subscribe
+// to it without re-calling the tool on every turn.
+type resourceArtifact struct {
+	Name        string // URI path segment under fi://user/{userID}/
+	Title       string
+	Description string
+	Tool        string // datasource.ToolFetchXxx, shared with ToolList/topicToTool
+}
+
+var resourceArtifacts = []resourceArtifact{
+	{Name: "net_worth", Title: "Net Worth", Description: "Asset and liability breakdown making up the user's net worth.", Tool: datasource.ToolFetchNetWorth},
+	{Name: "credit_report", Title: "Credit Report", Description: "Credit score, loan details and account history.", Tool: datasource.ToolFetchCreditReport},
+	{Name: "epf_details", Title: "EPF Details", Description: "Employee Provident Fund account and contribution history.", Tool: datasource.ToolFetchEPFDetails},
+	{Name: "mf_transactions", Title: "Mutual Fund Transactions", Description: "Mutual fund transaction history.", Tool: datasource.ToolFetchMFTransactions},
+	{Name: "bank_transactions", Title: "Bank Transactions", Description: "Bank account transaction history.", Tool: datasource.ToolFetchBankTransactions},
+	{Name: "stock_transactions", Title: "Stock Transactions", Description: "Indian stock transaction history.", Tool: datasource.ToolFetchStockTransactions},
+}
+
+// resourceURI returns the fi:// URI artifact is served under for userID.
+func resourceURI(userID, artifact string) string {
+	return fmt.Sprintf("fi://user/%s/%s", userID, artifact)
+}
+
+// registerUserResources exposes one resource per resourceArtifacts entry
+// for userID. mcp-go v0.33.0 only has a global resource registry (no
+// per-session equivalent of AddSessionTool), so every logged-in user's
+// resources live in the same registry; resources/list is scoped to the
+// requesting session instead at the HTTP layer, by serveFilteredResourcesList,
+// and ReadResource is scoped by resourceReadHandler re-authorizing on every
+// read. completeAuthorization calls this once login succeeds;
+// unregisterUserResources undoes it when a token refresh fails or the
+// client's MCP session is unregistered.
+func registerUserResources(userID string) {
+	if mcpServer == nil {
+		return
+	}
+	for _, a := range resourceArtifacts {
+		mcpServer.AddResource(
+			mcp.NewResource(
+				resourceURI(userID, a.Name),
+				a.Title,
+				mcp.WithResourceDescription(a.Description),
+				mcp.WithMIMEType("application/json"),
+			),
+			resourceReadHandler(userID, a),
+		)
+	}
+}
+
+// unregisterUserResources removes every resource registerUserResources
+// added for userID.
+func unregisterUserResources(userID string) {
+	if mcpServer == nil {
+		return
+	}
+	for _, a := range resourceArtifacts {
+		mcpServer.RemoveResource(resourceURI(userID, a.Name))
+	}
+}
+
+// resourceContent is the envelope every resource's JSON content is wrapped
+// in, so a client can tell which schema Data conforms to without parsing it
+// first.
+type resourceContent struct {
+	Schema string          `json:"$schema"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// resourceReadHandler returns the ResourceHandlerFunc backing userID's
+// artifact resource. Since the resource itself is registered globally, it
+// re-authorizes on every read: the calling session must be logged in as
+// the same userID the resource was registered for, the same check
+// AuthMiddleware applies before a tool call is allowed to proceed. Once
+// authorized, it dispatches to activeDataSource the same way toolHandler
+// does for the equivalent fetch_* tool.
+func resourceReadHandler(userID string, a resourceArtifact) server.ResourceHandlerFunc {
+	schemaURI := fmt.Sprintf("%s/%s.json", resourceSchemaBase, a.Tool)
+	return func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		sessionId := server.ClientSessionFromContext(ctx).SessionID()
+		sess := authMiddleware.getSession(ctx, sessionId)
+		if sess == nil || sess.Identity != userID {
+			return nil, fmt.Errorf("resource %s: not authorized", req.Params.URI)
+		}
+
+		fetch, ok := toolFetchByName[a.Tool]
+		if !ok {
+			return nil, fmt.Errorf("resource %s: no data source binding for %s", req.Params.URI, a.Tool)
+		}
+		body, err := fetch(activeDataSource, ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("resource %s: %w", req.Params.URI, err)
+		}
+
+		content, err := json.Marshal(resourceContent{Schema: schemaURI, Data: body})
+		if err != nil {
+			return nil, fmt.Errorf("resource %s: encoding content: %w", req.Params.URI, err)
+		}
+
+		return []mcp.ResourceContents{
+			mcp.TextResourceContents{
+				URI:      req.Params.URI,
+				MIMEType: "application/json",
+				Text:     string(content),
+			},
+		}, nil
+	}
+}
+
+// mcpSessionIDHeader is the header mcp-go's streamable HTTP transport reads
+// the session ID from on every request after the initial one.
+const mcpSessionIDHeader = "Mcp-Session-Id"
+
+// isResourcesListRequest reports whether r's JSON-RPC body is a
+// resources/list call, restoring r.Body afterward so the real handler can
+// still read it.
+func isResourcesListRequest(r *http.Request) bool {
+	raw, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+	if err != nil {
+		return false
+	}
+
+	var msg struct {
+		Method mcp.MCPMethod `json:"method"`
+	}
+	return json.Unmarshal(raw, &msg) == nil && msg.Method == mcp.MethodResourcesList
+}
+
+// responseRecorder buffers an http.ResponseWriter's output so
+// serveFilteredResourcesList can inspect and rewrite the body before any of
+// it reaches the client.
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{header: make(http.Header), status: http.StatusOK}
+}
+
+func (rr *responseRecorder) Header() http.Header         { return rr.header }
+func (rr *responseRecorder) WriteHeader(status int)      { rr.status = status }
+func (rr *responseRecorder) Write(b []byte) (int, error) { return rr.body.Write(b) }
+
+// serveFilteredResourcesList runs a resources/list request against
+// streamableServer and filters the result down to the resources owned by
+// the calling session's identity before forwarding it to w. mcp-go v0.33.0's
+// resources/list handler has no session-aware filtering hook - it only
+// supports per-session tools, not per-session resources - so without this
+// every connected client would see every logged-in user's
+// fi://user/{userID}/... resource URIs, leaking other users' identities.
+func serveFilteredResourcesList(streamableServer *server.StreamableHTTPServer, w http.ResponseWriter, r *http.Request) {
+	rec := newResponseRecorder()
+	streamableServer.ServeHTTP(rec, r)
+
+	body := rec.body.Bytes()
+	if rec.header.Get("Content-Type") == "application/json" {
+		if filtered, ok := filterResourcesListResponse(body, callerIdentity(r)); ok {
+			body = filtered
+		}
+	}
+
+	for k, v := range rec.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(rec.status)
+	w.Write(body)
+}
+
+// callerIdentity returns the Identity of the session r's Mcp-Session-Id
+// header names, or "" if there isn't one or it isn't logged in.
+func callerIdentity(r *http.Request) string {
+	sessionId := r.Header.Get(mcpSessionIDHeader)
+	if sessionId == "" || authMiddleware == nil {
+		return ""
+	}
+	sess := authMiddleware.getSession(r.Context(), sessionId)
+	if sess == nil {
+		return ""
+	}
+	return sess.Identity
+}
+
+// filterResourcesListResponse parses body as a JSON-RPC resources/list
+// response and drops every resource whose URI isn't under
+// fi://user/{identity}/, returning the re-encoded body. It returns ok=false
+// (leaving body untouched) if body isn't a resources/list result, so
+// errors and other response shapes pass through unmodified.
+func filterResourcesListResponse(body []byte, identity string) ([]byte, bool) {
+	var resp struct {
+		JSONRPC string                   `json:"jsonrpc"`
+		ID      json.RawMessage          `json:"id"`
+		Result  *mcp.ListResourcesResult `json:"result,omitempty"`
+		Error   json.RawMessage          `json:"error,omitempty"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil || resp.Result == nil {
+		return body, false
+	}
+
+	prefix := resourceURI(identity, "")
+	owned := resp.Result.Resources[:0]
+	for _, res := range resp.Result.Resources {
+		if identity != "" && len(res.URI) >= len(prefix) && res.URI[:len(prefix)] == prefix {
+			owned = append(owned, res)
+		}
+	}
+	resp.Result.Resources = owned
+
+	filtered, err := json.Marshal(resp)
+	if err != nil {
+		return body, false
+	}
+	return filtered, true
+}
@@ -0,0 +1,112 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// firestoreDoc mirrors Session plus the absolute document expiry it was Put
+// with. ExpiresAt is the real OAuth token expiry (same field Session
+// carries, and what AuthMiddleware checks to decide whether to refresh);
+// TTLExpiresAt is the unrelated sessionTTL deadline, a Firestore-specific
+// deletion horizon enforced both by a TTL policy configured on the
+// collection (see deploy docs) and defensively rechecked on Get. Keeping
+// them separate matters: collapsing TTLExpiresAt into ExpiresAt (or vice
+// versa) would make either the token-refresh check or the document
+// lifetime wrong.
+type firestoreDoc struct {
+	UserID        string
+	Identity      string
+	IdentityClaim string
+	IssuerName    string
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+	TTLExpiresAt  time.Time
+	Scopes        []string
+	AccessToken   string
+	RefreshToken  string
+}
+
+// FirestoreStore persists sessions in a Firestore collection, for Cloud
+// Run/Functions deployments.
+type FirestoreStore struct {
+	client     *firestore.Client
+	collection string
+}
+
+// NewFirestoreStore returns a FirestoreStore keeping one document per
+// session in collection.
+func NewFirestoreStore(client *firestore.Client, collection string) *FirestoreStore {
+	return &FirestoreStore{client: client, collection: collection}
+}
+
+func (s *FirestoreStore) doc(id string) *firestore.DocumentRef {
+	return s.client.Collection(s.collection).Doc(id)
+}
+
+func (s *FirestoreStore) Get(ctx context.Context, id string) (*Session, bool, error) {
+	snap, err := s.doc(id).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("session: firestore get: %w", err)
+	}
+
+	var d firestoreDoc
+	if err := snap.DataTo(&d); err != nil {
+		return nil, false, fmt.Errorf("session: decoding session: %w", err)
+	}
+	if time.Now().After(d.TTLExpiresAt) {
+		return nil, false, nil
+	}
+
+	return &Session{
+		UserID:        d.UserID,
+		Identity:      d.Identity,
+		IdentityClaim: d.IdentityClaim,
+		IssuerName:    d.IssuerName,
+		IssuedAt:      d.IssuedAt,
+		ExpiresAt:     d.ExpiresAt,
+		Scopes:        d.Scopes,
+		TokenBundle: TokenBundle{
+			AccessToken:  d.AccessToken,
+			RefreshToken: d.RefreshToken,
+		},
+	}, true, nil
+}
+
+func (s *FirestoreStore) Put(ctx context.Context, id string, sess *Session, ttl time.Duration) error {
+	d := firestoreDoc{
+		UserID:        sess.UserID,
+		Identity:      sess.Identity,
+		IdentityClaim: sess.IdentityClaim,
+		IssuerName:    sess.IssuerName,
+		IssuedAt:      sess.IssuedAt,
+		ExpiresAt:     sess.ExpiresAt,
+		TTLExpiresAt:  time.Now().Add(ttl),
+		Scopes:        sess.Scopes,
+		AccessToken:   sess.AccessToken,
+		RefreshToken:  sess.RefreshToken,
+	}
+	if _, err := s.doc(id).Set(ctx, d); err != nil {
+		return fmt.Errorf("session: firestore set: %w", err)
+	}
+	return nil
+}
+
+func (s *FirestoreStore) Delete(ctx context.Context, id string) error {
+	if _, err := s.doc(id).Delete(ctx); err != nil {
+		return fmt.Errorf("session: firestore delete: %w", err)
+	}
+	return nil
+}
+
+func (s *FirestoreStore) Refresh(ctx context.Context, id string, sess *Session, ttl time.Duration) error {
+	return s.Put(ctx, id, sess, ttl)
+}
@@ -0,0 +1,48 @@
+package session
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// TestFirestoreStoreIntegration exercises FirestoreStore against the
+// Firestore emulator (gcloud beta emulators firestore start). It's skipped
+// unless FIRESTORE_EMULATOR_HOST is set, matching how every other
+// emulator-backed Firestore test in the Go ecosystem gates itself.
+func TestFirestoreStoreIntegration(t *testing.T) {
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST not set; start the Firestore emulator to run this test")
+	}
+
+	ctx := context.Background()
+	client, err := firestore.NewClient(ctx, "fi-mcp-test")
+	if err != nil {
+		t.Fatalf("creating firestore client: %v", err)
+	}
+	defer client.Close()
+
+	s := NewFirestoreStore(client, "sessions-test")
+	sess := &Session{Identity: "1111111111", TokenBundle: TokenBundle{AccessToken: "at-1"}}
+
+	if err := s.Put(ctx, "sess-1", sess, time.Minute); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+	got, ok, err := s.Get(ctx, "sess-1")
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if !ok || got.Identity != "1111111111" {
+		t.Fatalf("Get = %+v, %v; want the session just Put", got, ok)
+	}
+
+	if err := s.Delete(ctx, "sess-1"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, ok, _ := s.Get(ctx, "sess-1"); ok {
+		t.Error("expected session to be gone after Delete")
+	}
+}
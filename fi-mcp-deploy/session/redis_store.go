@@ -0,0 +1,64 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore persists sessions in Redis, JSON-encoded under prefix+id, for
+// self-hosted deployments that run more than one instance.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore returns a RedisStore using client, namespacing keys under
+// prefix (e.g. "fi-mcp:session:").
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) key(id string) string {
+	return s.prefix + id
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (*Session, bool, error) {
+	raw, err := s.client.Get(ctx, s.key(id)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("session: redis get: %w", err)
+	}
+	var sess Session
+	if err := json.Unmarshal(raw, &sess); err != nil {
+		return nil, false, fmt.Errorf("session: decoding session: %w", err)
+	}
+	return &sess, true, nil
+}
+
+func (s *RedisStore) Put(ctx context.Context, id string, sess *Session, ttl time.Duration) error {
+	raw, err := json.Marshal(sess)
+	if err != nil {
+		return fmt.Errorf("session: encoding session: %w", err)
+	}
+	if err := s.client.Set(ctx, s.key(id), raw, ttl).Err(); err != nil {
+		return fmt.Errorf("session: redis set: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	if err := s.client.Del(ctx, s.key(id)).Err(); err != nil {
+		return fmt.Errorf("session: redis del: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Refresh(ctx context.Context, id string, sess *Session, ttl time.Duration) error {
+	return s.Put(ctx, id, sess, ttl)
+}
@@ -0,0 +1,55 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedisStore(t *testing.T) *RedisStore {
+	t.Helper()
+	mr, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("starting miniredis: %v", err)
+	}
+	t.Cleanup(mr.Close)
+
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client, "fi-mcp:session:")
+}
+
+func TestRedisStorePutGetDelete(t *testing.T) {
+	s := newTestRedisStore(t)
+	ctx := context.Background()
+
+	sess := &Session{Identity: "1111111111", TokenBundle: TokenBundle{AccessToken: "at-1"}}
+	if err := s.Put(ctx, "sess-1", sess, time.Minute); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	got, ok, err := s.Get(ctx, "sess-1")
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if !ok || got.Identity != "1111111111" || got.AccessToken != "at-1" {
+		t.Fatalf("Get = %+v, %v; want the session just Put", got, ok)
+	}
+
+	if err := s.Delete(ctx, "sess-1"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, ok, _ := s.Get(ctx, "sess-1"); ok {
+		t.Error("expected session to be gone after Delete")
+	}
+}
+
+func TestRedisStoreGetMissing(t *testing.T) {
+	s := newTestRedisStore(t)
+	if _, ok, err := s.Get(context.Background(), "no-such-session"); ok || err != nil {
+		t.Errorf("Get(missing) = ok=%v, err=%v; want ok=false, err=nil", ok, err)
+	}
+}
@@ -0,0 +1,129 @@
+// Package session defines the Store AuthMiddleware keeps verified
+// logins in, and provides the implementations this server can be deployed
+// with: an in-process store for a single instance, and Firestore/Redis
+// backed stores for deployments that run more than one.
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBundle is the OAuth token pair a session carries so AuthMiddleware
+// can call downstream APIs and transparently refresh an expired session.
+type TokenBundle struct {
+	AccessToken  string
+	RefreshToken string
+}
+
+// Session is what Store keeps per MCP session once a user has completed
+// the OIDC login flow. Identity is the verified subject claim (this
+// server uses it as the DataSource userID); UserID is carried separately
+// so a future identity provider can map one verified subject to a stable
+// internal user id without changing every call site. IdentityClaim records
+// which ID token claim Identity came from ("phone_number" or "sub"), so
+// callers can tell a test-fixture-compatible identity from an arbitrary
+// OIDC subject without re-parsing the token.
+type Session struct {
+	UserID        string
+	Identity      string
+	IdentityClaim string
+	IssuerName    string
+	IssuedAt      time.Time
+	ExpiresAt     time.Time
+	Scopes        []string
+	TokenBundle
+}
+
+// Store is implemented by every session backend AuthMiddleware can use.
+// Put creates or overwrites a session; Refresh is the same operation under
+// a name that makes call sites read as "this session's tokens just
+// changed" rather than "this is a new login".
+type Store interface {
+	Get(ctx context.Context, id string) (*Session, bool, error)
+	Put(ctx context.Context, id string, sess *Session, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+	Refresh(ctx context.Context, id string, sess *Session, ttl time.Duration) error
+}
+
+// janitorInterval is how often InMemoryStore sweeps for expired entries.
+const janitorInterval = time.Minute
+
+type inMemoryEntry struct {
+	sess      *Session
+	expiresAt time.Time
+}
+
+// InMemoryStore is a sync.RWMutex-guarded Store with a background janitor
+// goroutine expiring entries; it is correct for a single running instance
+// but, like a plain map, does not survive a restart or scale past one.
+type InMemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string]inMemoryEntry
+	done    chan struct{}
+}
+
+// NewInMemoryStore returns an empty InMemoryStore and starts its janitor.
+// Call Close to stop the janitor goroutine.
+func NewInMemoryStore() *InMemoryStore {
+	s := &InMemoryStore{
+		entries: make(map[string]inMemoryEntry),
+		done:    make(chan struct{}),
+	}
+	go s.janitor()
+	return s
+}
+
+func (s *InMemoryStore) janitor() {
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			now := time.Now()
+			s.mu.Lock()
+			for id, e := range s.entries {
+				if now.After(e.expiresAt) {
+					delete(s.entries, id)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// Close stops the janitor goroutine. The store must not be used afterward.
+func (s *InMemoryStore) Close() {
+	close(s.done)
+}
+
+func (s *InMemoryStore) Get(_ context.Context, id string) (*Session, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[id]
+	if !ok || time.Now().After(e.expiresAt) {
+		return nil, false, nil
+	}
+	return e.sess, true, nil
+}
+
+func (s *InMemoryStore) Put(_ context.Context, id string, sess *Session, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[id] = inMemoryEntry{sess: sess, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *InMemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, id)
+	return nil
+}
+
+func (s *InMemoryStore) Refresh(ctx context.Context, id string, sess *Session, ttl time.Duration) error {
+	return s.Put(ctx, id, sess, ttl)
+}
@@ -0,0 +1,64 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInMemoryStorePutGetDelete(t *testing.T) {
+	s := NewInMemoryStore()
+	defer s.Close()
+	ctx := context.Background()
+
+	sess := &Session{UserID: "1111111111", Identity: "1111111111"}
+	if err := s.Put(ctx, "sess-1", sess, time.Minute); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+
+	got, ok, err := s.Get(ctx, "sess-1")
+	if err != nil {
+		t.Fatalf("Get returned error: %v", err)
+	}
+	if !ok || got.Identity != "1111111111" {
+		t.Fatalf("Get = %v, %v; want the session just Put", got, ok)
+	}
+
+	if err := s.Delete(ctx, "sess-1"); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+	if _, ok, _ := s.Get(ctx, "sess-1"); ok {
+		t.Error("expected session to be gone after Delete")
+	}
+}
+
+func TestInMemoryStoreExpiry(t *testing.T) {
+	s := NewInMemoryStore()
+	defer s.Close()
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "sess-1", &Session{Identity: "u1"}, time.Millisecond); err != nil {
+		t.Fatalf("Put returned error: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok, _ := s.Get(ctx, "sess-1"); ok {
+		t.Error("expected expired session to be absent")
+	}
+}
+
+func TestInMemoryStoreRefresh(t *testing.T) {
+	s := NewInMemoryStore()
+	defer s.Close()
+	ctx := context.Background()
+
+	s.Put(ctx, "sess-1", &Session{Identity: "u1", TokenBundle: TokenBundle{AccessToken: "at-1"}}, time.Minute)
+	if err := s.Refresh(ctx, "sess-1", &Session{Identity: "u1", TokenBundle: TokenBundle{AccessToken: "at-2"}}, time.Minute); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+
+	got, ok, _ := s.Get(ctx, "sess-1")
+	if !ok || got.AccessToken != "at-2" {
+		t.Fatalf("Get after Refresh = %v, %v; want AccessToken at-2", got, ok)
+	}
+}